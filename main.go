@@ -8,11 +8,15 @@ import (
 	"time"
 
 	"github.com/macadmins/osquery-extension/tables/alt_system_info"
+	"github.com/macadmins/osquery-extension/tables/chef"
+	"github.com/macadmins/osquery-extension/tables/chromepolicies"
 	"github.com/macadmins/osquery-extension/tables/chromeuserprofiles"
 	"github.com/macadmins/osquery-extension/tables/crowdstrike_falcon"
+	"github.com/macadmins/osquery-extension/tables/editorextensions"
 	"github.com/macadmins/osquery-extension/tables/energyimpact"
 	"github.com/macadmins/osquery-extension/tables/fileline"
 	"github.com/macadmins/osquery-extension/tables/filevaultusers"
+	"github.com/macadmins/osquery-extension/tables/firefox"
 	"github.com/macadmins/osquery-extension/tables/localnetworkpermissions"
 	macosprofiles "github.com/macadmins/osquery-extension/tables/macos_profiles"
 	"github.com/macadmins/osquery-extension/tables/macosrsr"
@@ -68,10 +72,18 @@ func main() {
 	// Adding a new table? Add it to the list and the loop below will handle
 	// the registration for you.
 	plugins := []osquery.OsqueryPlugin{
+		table.NewPlugin("puppet_agent_status", puppet.PuppetAgentStatusColumns(), puppet.PuppetAgentStatusGenerate),
+		table.NewPlugin("puppet_events", puppet.PuppetEventsColumns(), puppet.PuppetEventsGenerate),
 		table.NewPlugin("puppet_info", puppet.PuppetInfoColumns(), puppet.PuppetInfoGenerate),
 		table.NewPlugin("puppet_logs", puppet.PuppetLogsColumns(), puppet.PuppetLogsGenerate),
+		table.NewPlugin("puppet_report_history", puppet.PuppetReportHistoryColumns(), puppet.PuppetReportHistoryGenerate),
+		table.NewPlugin("puppet_run_summary", puppet.PuppetRunSummaryColumns(), puppet.PuppetRunSummaryGenerate),
 		table.NewPlugin("puppet_state", puppet.PuppetStateColumns(), puppet.PuppetStateGenerate),
 		table.NewPlugin("puppet_facts", puppet.PuppetFactsColumns(), puppet.PuppetFactsGenerate),
+		table.NewPlugin("browser_profiles", chromeuserprofiles.BrowserProfilesColumns(), chromeuserprofiles.BrowserProfilesGenerate),
+		table.NewPlugin("chef_run", chef.ChefRunColumns(), chef.ChefRunGenerate),
+		table.NewPlugin("chef_updated_resources", chef.ChefUpdatedResourcesColumns(), chef.ChefUpdatedResourcesGenerate),
+		table.NewPlugin("editor_extensions", editorextensions.EditorExtensionsColumns(), editorextensions.EditorExtensionsGenerate),
 		table.NewPlugin("google_chrome_profiles", chromeuserprofiles.GoogleChromeProfilesColumns(), chromeuserprofiles.GoogleChromeProfilesGenerate),
 		table.NewPlugin("file_lines", fileline.FileLineColumns(), fileline.FileLineGenerate),
 	}
@@ -83,6 +95,9 @@ func main() {
 
 	if runtime.GOOS == "linux" || runtime.GOOS == "darwin" {
 		linuxPlugins := []osquery.OsqueryPlugin{
+			table.NewPlugin("chrome_policies", chromepolicies.ChromePoliciesColumns(), chromepolicies.ChromePoliciesGenerate),
+			table.NewPlugin("firefox_policies", firefox.FirefoxPoliciesColumns(), firefox.FirefoxPoliciesGenerate),
+			table.NewPlugin("firefox_profiles", firefox.FirefoxProfilesColumns(), firefox.FirefoxProfilesGenerate),
 			table.NewPlugin(
 				"crowdstrike_falcon",
 				crowdstrike_falcon.CrowdstrikeFalconColumns(),
@@ -100,8 +115,17 @@ func main() {
 			table.NewPlugin("local_network_permissions", localnetworkpermissions.LocalNetworkPermissionsColumns(), localnetworkpermissions.LocalNetworkPermissionsGenerate),
 			table.NewPlugin("macos_profiles", macosprofiles.MacOSProfilesColumns(), macosprofiles.MacOSProfilesGenerate),
 			table.NewPlugin("mdm", mdm.MDMInfoColumns(), mdm.MDMInfoGenerate),
+			table.NewPlugin("munki_catalog_items", munki.MunkiCatalogItemsColumns(), munki.MunkiCatalogItemsGenerate),
+			table.NewPlugin("munki_conditions", munki.MunkiConditionsColumns(), munki.MunkiConditionsGenerate),
 			table.NewPlugin("munki_info", munki.MunkiInfoColumns(), munki.MunkiInfoGenerate),
 			table.NewPlugin("munki_installs", munki.MunkiInstallsColumns(), munki.MunkiInstallsGenerate),
+			table.NewPlugin("munki_logs", munki.MunkiLogsColumns(), munki.MunkiLogsGenerate),
+			table.NewPlugin("munki_manifests", munki.MunkiManifestsColumns(), munki.MunkiManifestsGenerate),
+			table.NewPlugin("munki_pending_items", munki.MunkiPendingItemsColumns(), munki.MunkiPendingItemsGenerate),
+			table.NewPlugin("munki_preferences", munki.MunkiPreferencesColumns(), munki.MunkiPreferencesGenerate),
+			table.NewPlugin("munki_run_history", munki.MunkiRunHistoryColumns(), munki.MunkiRunHistoryGenerate),
+			table.NewPlugin("munki_install_results", munki.MunkiInstallResultsColumns(), munki.MunkiInstallResultsGenerate),
+			table.NewPlugin("munki_self_service", munki.MunkiSelfServiceColumns(), munki.MunkiSelfServiceGenerate),
 			table.NewPlugin("network_quality", networkquality.NetworkQualityColumns(), networkquality.NetworkQualityGenerate),
 			table.NewPlugin("pending_apple_updates", pendingappleupdates.PendingAppleUpdatesColumns(), pendingappleupdates.PendingAppleUpdatesGenerate),
 			table.NewPlugin("macadmins_unified_log", unifiedlog.UnifiedLogColumns(), unifiedlog.UnifiedLogGenerate),
@@ -112,6 +136,9 @@ func main() {
 			table.NewPlugin("sofa_unpatched_cves", sofa.SofaUnpatchedCVEsColumns(), func(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
 				return sofa.SofaUnpatchedCVEsGenerate(ctx, queryContext, *flSocketPath, sofaOpts...)
 			}),
+			table.NewPlugin("sofa_version_status", sofa.SofaVersionStatusColumns(), func(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+				return sofa.SofaVersionStatusGenerate(ctx, queryContext, *flSocketPath, sofaOpts...)
+			}),
 			table.NewPlugin("authdb", authdb.AuthDBColumns(), authdb.AuthDBGenerate),
 			table.NewPlugin(
 				"wifi_network",
@@ -0,0 +1,26 @@
+package feed
+
+import (
+	"encoding/json"
+
+	"github.com/micromdm/plist"
+	"gopkg.in/yaml.v3"
+)
+
+// Decoder turns the raw bytes of a feed into v.
+type Decoder func(data []byte, v interface{}) error
+
+// JSON decodes feeds published as JSON.
+func JSON(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// Plist decodes feeds published as XML or binary property lists.
+func Plist(data []byte, v interface{}) error {
+	return plist.Unmarshal(data, v)
+}
+
+// YAML decodes feeds published as YAML.
+func YAML(data []byte, v interface{}) error {
+	return yaml.Unmarshal(data, v)
+}
@@ -0,0 +1,349 @@
+// Package feed downloads remote reference datasets and caches them on disk.
+//
+// A Fetcher issues conditional requests using the ETag of the cached copy,
+// transparently handles gzip responses, enforces a size limit and falls back
+// to the cached copy when the remote cannot be reached.
+package feed
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/macadmins/osquery-extension/pkg/utils"
+)
+
+const (
+	// DefaultMaxSize is the largest response body a Fetcher accepts unless
+	// WithMaxSize is used.
+	DefaultMaxSize int64 = 64 << 20
+	// DefaultTimeout is the timeout of the HTTP client used when none is
+	// provided with WithHTTPClient.
+	DefaultTimeout = 10 * time.Second
+
+	dataFileName = "data"
+	etagFileName = "etag"
+)
+
+// ErrTooLarge is returned when a response body exceeds the maximum size.
+var ErrTooLarge = errors.New("feed exceeds maximum size")
+
+type Fetcher struct {
+	url        string
+	name       string
+	cacheDir   string
+	cacheFile  string
+	etagFile   string
+	ttl        time.Duration
+	maxSize    int64
+	userAgent  string
+	offline    bool
+	httpClient *http.Client
+	decoder    Decoder
+	fs         utils.FileSystem
+}
+
+type Option func(*Fetcher)
+
+// WithName sets the name of the feed, which is used as the name of its cache
+// directory. It defaults to the last element of the URL path.
+func WithName(name string) Option {
+	return func(f *Fetcher) {
+		f.name = name
+	}
+}
+
+// WithCacheDir sets the directory the per-feed cache directories are created in.
+func WithCacheDir(cacheDir string) Option {
+	return func(f *Fetcher) {
+		f.cacheDir = cacheDir
+	}
+}
+
+// WithCacheFiles overrides the location of the cached data and its ETag.
+func WithCacheFiles(cacheFile, etagFile string) Option {
+	return func(f *Fetcher) {
+		f.cacheFile = cacheFile
+		f.etagFile = etagFile
+	}
+}
+
+// WithTTL sets how long a cached copy is used without contacting the remote.
+// A TTL of zero revalidates the cache on every fetch.
+func WithTTL(ttl time.Duration) Option {
+	return func(f *Fetcher) {
+		f.ttl = ttl
+	}
+}
+
+// WithMaxSize sets the largest decompressed response body that is accepted.
+func WithMaxSize(maxSize int64) Option {
+	return func(f *Fetcher) {
+		f.maxSize = maxSize
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(f *Fetcher) {
+		f.userAgent = userAgent
+	}
+}
+
+// WithOfflineFallback controls whether a stale cached copy is used when the
+// remote cannot be reached or returns an error. It is enabled by default.
+func WithOfflineFallback(enabled bool) Option {
+	return func(f *Fetcher) {
+		f.offline = enabled
+	}
+}
+
+func WithHTTPClient(client *http.Client) Option {
+	return func(f *Fetcher) {
+		f.httpClient = client
+	}
+}
+
+// WithDecoder sets the decoder used for the feed. It defaults to JSON.
+func WithDecoder(decoder Decoder) Option {
+	return func(f *Fetcher) {
+		f.decoder = decoder
+	}
+}
+
+func WithFileSystem(fs utils.FileSystem) Option {
+	return func(f *Fetcher) {
+		f.fs = fs
+	}
+}
+
+// New creates a Fetcher for the feed at rawURL and creates its cache directory.
+func New(rawURL string, opts ...Option) (*Fetcher, error) {
+	f := &Fetcher{
+		url:     rawURL,
+		maxSize: DefaultMaxSize,
+		offline: true,
+		httpClient: &http.Client{
+			Timeout: DefaultTimeout,
+		},
+		decoder:  JSON,
+		cacheDir: filepath.Join(os.TempDir(), "macadmins_extension"),
+	}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	if f.fs == nil {
+		f.fs = utils.OSFileSystem{}
+	}
+
+	if f.name == "" {
+		name, err := nameFromURL(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		f.name = name
+	}
+
+	if f.cacheFile == "" {
+		f.cacheFile = filepath.Join(f.cacheDir, f.name, dataFileName)
+	}
+	if f.etagFile == "" {
+		f.etagFile = filepath.Join(f.cacheDir, f.name, etagFileName)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.cacheFile), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.etagFile), 0755); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// nameFromURL derives a cache directory name from the last element of the URL
+// path, without its extension.
+func nameFromURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimSuffix(path.Base(u.Path), path.Ext(u.Path))
+	if name == "" || name == "." || name == "/" {
+		return "", fmt.Errorf("cannot derive feed name from %q, use WithName", rawURL)
+	}
+	return name, nil
+}
+
+// CacheFile returns the path of the cached copy of the feed.
+func (f *Fetcher) CacheFile() string {
+	return f.cacheFile
+}
+
+// Fetch decodes the feed into v. The cached copy is used while it is younger
+// than the TTL; otherwise the remote is asked for a newer copy, which is only
+// written to the cache once it has been decoded successfully.
+func (f *Fetcher) Fetch(v interface{}) error {
+	if f.cacheFresh() {
+		return f.decodeCache(v)
+	}
+
+	data, etag, err := f.download()
+	if err != nil {
+		if f.offline && utils.FileExists(f.fs, f.cacheFile) {
+			log.Printf("feed %s: using cached copy: %v", f.name, err)
+			return f.decodeCache(v)
+		}
+		return err
+	}
+
+	if data == nil {
+		// not modified
+		if err := f.touchCache(); err != nil {
+			return err
+		}
+		return f.decodeCache(v)
+	}
+
+	if err := f.decoder(data, v); err != nil {
+		if f.offline && utils.FileExists(f.fs, f.cacheFile) {
+			log.Printf("feed %s: decode download, using cached copy: %v", f.name, err)
+			return f.decodeCache(v)
+		}
+		return fmt.Errorf("decode feed %s: %w", f.name, err)
+	}
+
+	return f.writeCache(data, etag)
+}
+
+func (f *Fetcher) cacheFresh() bool {
+	if f.ttl <= 0 || !utils.FileExists(f.fs, f.cacheFile) {
+		return false
+	}
+	info, err := os.Stat(f.cacheFile)
+	if err != nil {
+		return false
+	}
+	return time.Since(info.ModTime()) < f.ttl
+}
+
+func (f *Fetcher) decodeCache(v interface{}) error {
+	data, err := os.ReadFile(f.cacheFile)
+	if err != nil {
+		return err
+	}
+	if err := f.decoder(data, v); err != nil {
+		return fmt.Errorf("decode cached feed %s: %w", f.name, err)
+	}
+	return nil
+}
+
+func (f *Fetcher) cachedEtag() string {
+	if !utils.FileExists(f.fs, f.cacheFile) || !utils.FileExists(f.fs, f.etagFile) {
+		return ""
+	}
+	etag, err := os.ReadFile(f.etagFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(etag))
+}
+
+// download requests the feed, returning a nil body when the server reports the
+// cached copy is current.
+func (f *Fetcher) download() ([]byte, string, error) {
+	req, err := http.NewRequest("GET", f.url, http.NoBody)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if f.userAgent != "" {
+		req.Header.Set("User-Agent", f.userAgent)
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+	if etag := f.cachedEtag(); etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, "", nil
+	default:
+		return nil, "", fmt.Errorf("fetch %s: unexpected status %s", f.url, resp.Status)
+	}
+
+	var reader io.Reader = resp.Body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, "", fmt.Errorf("fetch %s: %w", f.url, err)
+		}
+		defer func() {
+			if err := gzipReader.Close(); err != nil {
+				log.Printf("close gzip reader: %v", err)
+			}
+		}()
+		reader = gzipReader
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, f.maxSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("fetch %s: %w", f.url, err)
+	}
+	if int64(len(data)) > f.maxSize {
+		return nil, "", fmt.Errorf("fetch %s: %w (%d bytes)", f.url, ErrTooLarge, f.maxSize)
+	}
+
+	return data, resp.Header.Get("ETag"), nil
+}
+
+// writeCache replaces the cached copy atomically so a failed write never
+// leaves a partial feed behind.
+func (f *Fetcher) writeCache(data []byte, etag string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(f.cacheFile), "."+filepath.Base(f.cacheFile)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // nolint: errcheck
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close() // nolint: errcheck
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), f.cacheFile); err != nil {
+		return err
+	}
+
+	if etag == "" {
+		if err := os.Remove(f.etagFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(f.etagFile, []byte(etag), 0644)
+}
+
+func (f *Fetcher) touchCache() error {
+	now := time.Now()
+	return os.Chtimes(f.cacheFile, now, now)
+}
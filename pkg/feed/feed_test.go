@@ -0,0 +1,353 @@
+package feed
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testETag = `W/"123456789"`
+
+type testFeed struct {
+	Name    string `json:"name" plist:"name" yaml:"name"`
+	Version int    `json:"version" plist:"version" yaml:"version"`
+}
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *int32) {
+	t.Helper()
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// etagHandler serves body with testETag and honours If-None-Match.
+func etagHandler(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == testETag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", testETag)
+		w.Write([]byte(body)) //nolint:errcheck
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server, _ := newTestServer(t, etagHandler(`{"name": "test", "version": 1}`))
+	cacheDir := t.TempDir()
+
+	f, err := New(server.URL+"/v1/test_feed.json", WithCacheDir(cacheDir), WithUserAgent("test"))
+	require.NoError(t, err)
+
+	var got testFeed
+	require.NoError(t, f.Fetch(&got))
+	assert.Equal(t, testFeed{Name: "test", Version: 1}, got)
+
+	assert.Equal(t, filepath.Join(cacheDir, "test_feed", "data"), f.CacheFile())
+	assert.FileExists(t, f.CacheFile())
+	etag, err := os.ReadFile(filepath.Join(cacheDir, "test_feed", "etag"))
+	require.NoError(t, err)
+	assert.Equal(t, testETag, string(etag))
+}
+
+func TestFetchUserAgent(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Foo/2.0", r.Header.Get("User-Agent"))
+		w.Write([]byte(`{}`)) //nolint:errcheck
+	})
+
+	f, err := New(server.URL, WithName("ua"), WithCacheDir(t.TempDir()), WithUserAgent("Foo/2.0"))
+	require.NoError(t, err)
+
+	var got testFeed
+	assert.NoError(t, f.Fetch(&got))
+}
+
+func TestFetchNotModified(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 1 {
+			assert.Equal(t, testETag, r.Header.Get("If-None-Match"))
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", testETag)
+		w.Write([]byte(`{"name": "cached"}`)) //nolint:errcheck
+	}))
+	defer server.Close()
+
+	f, err := New(server.URL, WithName("not_modified"), WithCacheDir(t.TempDir()))
+	require.NoError(t, err)
+
+	var first, second testFeed
+	require.NoError(t, f.Fetch(&first))
+	require.NoError(t, f.Fetch(&second))
+	assert.Equal(t, "cached", second.Name)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestFetchServerError(t *testing.T) {
+	fail := int32(0)
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&fail) == 1 {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"name": "stale"}`)) //nolint:errcheck
+	})
+
+	t.Run("no cache", func(t *testing.T) {
+		atomic.StoreInt32(&fail, 1)
+		f, err := New(server.URL, WithName("error"), WithCacheDir(t.TempDir()))
+		require.NoError(t, err)
+
+		var got testFeed
+		err = f.Fetch(&got)
+		assert.ErrorContains(t, err, "500")
+	})
+
+	t.Run("offline fallback", func(t *testing.T) {
+		atomic.StoreInt32(&fail, 0)
+		f, err := New(server.URL, WithName("error"), WithCacheDir(t.TempDir()))
+		require.NoError(t, err)
+
+		var got testFeed
+		require.NoError(t, f.Fetch(&got))
+
+		atomic.StoreInt32(&fail, 1)
+		got = testFeed{}
+		require.NoError(t, f.Fetch(&got))
+		assert.Equal(t, "stale", got.Name)
+	})
+
+	t.Run("offline fallback disabled", func(t *testing.T) {
+		atomic.StoreInt32(&fail, 0)
+		f, err := New(server.URL, WithName("error"), WithCacheDir(t.TempDir()), WithOfflineFallback(false))
+		require.NoError(t, err)
+
+		var got testFeed
+		require.NoError(t, f.Fetch(&got))
+
+		atomic.StoreInt32(&fail, 1)
+		assert.Error(t, f.Fetch(&got))
+	})
+}
+
+func TestFetchUnreachable(t *testing.T) {
+	server, _ := newTestServer(t, etagHandler(`{"name": "offline"}`))
+	f, err := New(server.URL, WithName("unreachable"), WithCacheDir(t.TempDir()))
+	require.NoError(t, err)
+
+	var got testFeed
+	require.NoError(t, f.Fetch(&got))
+
+	server.Close()
+	got = testFeed{}
+	require.NoError(t, f.Fetch(&got))
+	assert.Equal(t, "offline", got.Name)
+}
+
+func TestFetchGzip(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(`{"name": "gzipped", "version": 2}`))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "gzip", r.Header.Get("Accept-Encoding"))
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(buf.Bytes()) //nolint:errcheck
+	})
+
+	f, err := New(server.URL, WithName("gzip"), WithCacheDir(t.TempDir()))
+	require.NoError(t, err)
+
+	var got testFeed
+	require.NoError(t, f.Fetch(&got))
+	assert.Equal(t, testFeed{Name: "gzipped", Version: 2}, got)
+
+	cached, err := os.ReadFile(f.CacheFile())
+	require.NoError(t, err)
+	assert.Equal(t, `{"name": "gzipped", "version": 2}`, string(cached))
+}
+
+func TestFetchTruncated(t *testing.T) {
+	body := `{"name": "truncated", "version": 3}`
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write([]byte(body[:10])) //nolint:errcheck
+	})
+
+	f, err := New(server.URL, WithName("truncated"), WithCacheDir(t.TempDir()))
+	require.NoError(t, err)
+
+	var got testFeed
+	assert.Error(t, f.Fetch(&got))
+	assert.NoFileExists(t, f.CacheFile())
+}
+
+func TestFetchTruncatedGzip(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(`{"name": "gzipped", "version": 2}`))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	truncated := buf.Bytes()[:buf.Len()/2]
+
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(truncated) //nolint:errcheck
+	})
+
+	f, err := New(server.URL, WithName("truncated_gzip"), WithCacheDir(t.TempDir()))
+	require.NoError(t, err)
+
+	var got testFeed
+	assert.Error(t, f.Fetch(&got))
+	assert.NoFileExists(t, f.CacheFile())
+}
+
+func TestFetchInvalidBodyKeepsCache(t *testing.T) {
+	broken := int32(0)
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&broken) == 1 {
+			w.Write([]byte(`{"name": `)) //nolint:errcheck
+			return
+		}
+		w.Write([]byte(`{"name": "good"}`)) //nolint:errcheck
+	})
+
+	f, err := New(server.URL, WithName("invalid"), WithCacheDir(t.TempDir()))
+	require.NoError(t, err)
+
+	var got testFeed
+	require.NoError(t, f.Fetch(&got))
+
+	atomic.StoreInt32(&broken, 1)
+	got = testFeed{}
+	require.NoError(t, f.Fetch(&got))
+	assert.Equal(t, "good", got.Name)
+
+	cached, err := os.ReadFile(f.CacheFile())
+	require.NoError(t, err)
+	assert.Equal(t, `{"name": "good"}`, string(cached))
+}
+
+func TestFetchMaxSize(t *testing.T) {
+	server, _ := newTestServer(t, etagHandler(`{"name": "this body is too large"}`))
+
+	f, err := New(server.URL, WithName("max_size"), WithCacheDir(t.TempDir()), WithMaxSize(8))
+	require.NoError(t, err)
+
+	var got testFeed
+	assert.ErrorIs(t, f.Fetch(&got), ErrTooLarge)
+}
+
+func TestFetchTTL(t *testing.T) {
+	server, requests := newTestServer(t, etagHandler(`{"name": "ttl"}`))
+
+	f, err := New(server.URL, WithName("ttl"), WithCacheDir(t.TempDir()), WithTTL(time.Hour))
+	require.NoError(t, err)
+
+	var got testFeed
+	require.NoError(t, f.Fetch(&got))
+	require.NoError(t, f.Fetch(&got))
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+
+	// expire the cache
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(f.CacheFile(), old, old))
+	require.NoError(t, f.Fetch(&got))
+	assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+
+	// a 304 refreshes the cache age
+	require.NoError(t, f.Fetch(&got))
+	assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+}
+
+func TestFetchDecoders(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		decoder Decoder
+	}{
+		{
+			name:    "json",
+			body:    `{"name": "decoded", "version": 4}`,
+			decoder: JSON,
+		},
+		{
+			name: "plist",
+			body: `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>name</key>
+	<string>decoded</string>
+	<key>version</key>
+	<integer>4</integer>
+</dict>
+</plist>`,
+			decoder: Plist,
+		},
+		{
+			name:    "yaml",
+			body:    "name: decoded\nversion: 4\n",
+			decoder: YAML,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t, etagHandler(tt.body))
+			f, err := New(server.URL, WithName(tt.name), WithCacheDir(t.TempDir()), WithDecoder(tt.decoder))
+			require.NoError(t, err)
+
+			var got testFeed
+			require.NoError(t, f.Fetch(&got))
+			assert.Equal(t, testFeed{Name: "decoded", Version: 4}, got)
+		})
+	}
+}
+
+func TestWithCacheFiles(t *testing.T) {
+	server, _ := newTestServer(t, etagHandler(`{"name": "files"}`))
+	dir := t.TempDir()
+	cacheFile := filepath.Join(dir, "cache", "feed.json")
+	etagFile := filepath.Join(dir, "etag", "feed_etag.txt")
+
+	f, err := New(server.URL, WithName("files"), WithCacheFiles(cacheFile, etagFile))
+	require.NoError(t, err)
+
+	var got testFeed
+	require.NoError(t, f.Fetch(&got))
+	assert.FileExists(t, cacheFile)
+	assert.FileExists(t, etagFile)
+}
+
+func TestNameFromURL(t *testing.T) {
+	name, err := nameFromURL("https://sofafeed.macadmins.io/v1/macos_data_feed.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "macos_data_feed", name)
+
+	_, err = nameFromURL("https://example.com/")
+	assert.Error(t, err)
+
+	_, err = New("https://example.com/", WithCacheDir(t.TempDir()))
+	assert.Error(t, err)
+}
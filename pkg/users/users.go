@@ -0,0 +1,266 @@
+package users
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/micromdm/plist"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/pkg/errors"
+)
+
+// User is a local account that can log in, and so may have per-user files in
+// its home.
+type User struct {
+	Username string
+	UID      string
+	Home     string
+}
+
+// Lister lists the users of the system.
+type Lister interface {
+	List(opts ...Option) ([]User, error)
+}
+
+type listOptions struct {
+	usernames map[string]bool
+}
+
+type Option func(*listOptions)
+
+// WithUsername limits the list to the named user. It can be given more than
+// once.
+func WithUsername(username string) Option {
+	return func(o *listOptions) {
+		if o.usernames == nil {
+			o.usernames = map[string]bool{}
+		}
+		o.usernames[username] = true
+	}
+}
+
+// WithUsernames limits the list to the users named by `username = ...`
+// constraints, if the query has any.
+func WithUsernames(queryContext table.QueryContext) Option {
+	return func(o *listOptions) {
+		constraintList, present := queryContext.Constraints["username"]
+		if !present {
+			return
+		}
+		for _, constraint := range constraintList.Constraints {
+			if constraint.Operator == table.OperatorEquals {
+				WithUsername(constraint.Expression)(o)
+			}
+		}
+	}
+}
+
+func (o listOptions) match(user User) bool {
+	return len(o.usernames) == 0 || o.usernames[user.Username]
+}
+
+var (
+	passwdPath      = "/etc/passwd"
+	dslocalUsersDir = "/var/db/dslocal/nodes/Default/users"
+)
+
+// homeDirRoots hold the homes of users that are not local accounts, such as
+// directory service users on Linux.
+var homeDirRoots = map[string]string{
+	"windows": "C:\\Users",
+	"darwin":  "/Users",
+	"linux":   "/home",
+}
+
+const (
+	// the first UIDs given to accounts that are not system accounts
+	passwdMinUID  = 1000
+	dslocalMinUID = 500
+
+	// nobodyUID is the overflow UID, which is above passwdMinUID.
+	nobodyUID = 65534
+)
+
+// sharedHomes are the directories in the home directory roots that are not
+// the home of a user.
+var sharedHomes = map[string]bool{
+	"All Users":    true,
+	"Default":      true,
+	"Default User": true,
+	"Public":       true,
+	"Shared":       true,
+	"lost+found":   true,
+}
+
+// OSLister lists users from the local account database: /etc/passwd on Linux
+// and the dslocal node on macOS. Homes in the default home directory root that
+// belong to no local account are listed as users named after the directory.
+type OSLister struct{}
+
+func (OSLister) List(opts ...Option) ([]User, error) {
+	o := listOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var accounts, system []User
+	var err error
+	switch runtime.GOOS {
+	case "darwin":
+		accounts, system, err = dslocalUsers(dslocalUsersDir)
+	case "linux":
+		accounts, system, err = passwdUsers(passwdPath)
+	}
+	if err != nil {
+		// reading the account database may need root, the homes should not
+		log.Printf("error listing local accounts: %s", err)
+	}
+	// the homes of system accounts, such as /home/syslog, are not users either
+	known := append(append([]User{}, accounts...), system...)
+	accounts = append(accounts, unlistedHomes(homeDirRoots[runtime.GOOS], known)...)
+
+	var results []User
+	for _, user := range accounts {
+		if o.match(user) {
+			results = append(results, user)
+		}
+	}
+	return results, nil
+}
+
+func isSystemAccount(username string, uid, minUID int, shell string) bool {
+	if uid < minUID || uid == nobodyUID {
+		return true
+	}
+	if strings.HasPrefix(username, "_") {
+		return true
+	}
+	return strings.HasSuffix(shell, "/nologin") || strings.HasSuffix(shell, "/false")
+}
+
+// passwdUsers reads the accounts in a passwd(5) file, returning the system
+// accounts separately.
+func passwdUsers(path string) (users, system []User, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, errors.Wrap(err, "open passwd")
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("error closing passwd: %s", err)
+		}
+	}()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// name:password:UID:GID:GECOS:directory:shell
+		fields := strings.Split(line, ":")
+		if len(fields) < 7 {
+			continue
+		}
+		uid, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+		user := User{Username: fields[0], UID: fields[2], Home: fields[5]}
+		if isSystemAccount(fields[0], uid, passwdMinUID, fields[6]) {
+			system = append(system, user)
+			continue
+		}
+		users = append(users, user)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, errors.Wrap(err, "read passwd")
+	}
+	return users, system, nil
+}
+
+// dslocalUser is a user record of the dslocal node. Every attribute is a list.
+type dslocalUser struct {
+	Name  []string `plist:"name"`
+	UID   []string `plist:"uid"`
+	Home  []string `plist:"home"`
+	Shell []string `plist:"shell"`
+}
+
+// dslocalUsers reads the accounts in the user records of a dslocal node,
+// returning the system accounts separately.
+func dslocalUsers(dir string) (users, system []User, err error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.plist"))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "list dslocal users")
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "read dslocal user")
+		}
+		var record dslocalUser
+		if err := plist.Unmarshal(data, &record); err != nil {
+			log.Printf("error decoding dslocal user %s: %s", path, err)
+			continue
+		}
+		if len(record.Name) == 0 || len(record.UID) == 0 || len(record.Home) == 0 {
+			continue
+		}
+		uid, err := strconv.Atoi(record.UID[0])
+		if err != nil {
+			continue
+		}
+		shell := ""
+		if len(record.Shell) > 0 {
+			shell = record.Shell[0]
+		}
+		user := User{Username: record.Name[0], UID: record.UID[0], Home: record.Home[0]}
+		if isSystemAccount(record.Name[0], uid, dslocalMinUID, shell) {
+			system = append(system, user)
+			continue
+		}
+		users = append(users, user)
+	}
+	return users, system, nil
+}
+
+// unlistedHomes returns a user for each directory in root that is not the home
+// or the name of one of accounts.
+func unlistedHomes(root string, accounts []User) []User {
+	if root == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil
+	}
+
+	known := map[string]bool{}
+	for _, user := range accounts {
+		known[filepath.Clean(user.Home)] = true
+		known[user.Username] = true
+	}
+
+	var results []User
+	for _, entry := range entries {
+		home := filepath.Join(root, entry.Name())
+		if !entry.IsDir() || known[home] || known[entry.Name()] {
+			continue
+		}
+		if strings.HasPrefix(entry.Name(), ".") || sharedHomes[entry.Name()] {
+			continue
+		}
+		results = append(results, User{Username: entry.Name(), Home: home})
+	}
+	return results
+}
@@ -0,0 +1,25 @@
+package users
+
+// MockLister is a mock implementation of Lister for testing. Options are
+// applied to Users as OSLister would.
+type MockLister struct {
+	Users []User
+	Err   error
+}
+
+func (m MockLister) List(opts ...Option) ([]User, error) {
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	o := listOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var results []User
+	for _, user := range m.Users {
+		if o.match(user) {
+			results = append(results, user)
+		}
+	}
+	return results, nil
+}
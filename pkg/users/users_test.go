@@ -0,0 +1,186 @@
+package users
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPasswd = `# local accounts
+root:x:0:0:root:/root:/bin/bash
+daemon:x:1:1:daemon:/usr/sbin:/usr/sbin/nologin
+systemd-network:x:998:998:systemd Network Management:/:/usr/sbin/nologin
+alice:x:1000:1000:Alice,,,:/home/alice:/bin/bash
+bob:x:1001:1001::/srv/bob:/bin/zsh
+nobody:x:65534:65534:nobody:/nonexistent:/usr/sbin/nologin
+svc:x:1002:1002::/home/svc:/bin/false
+broken line
+`
+
+const testDSLocalUser = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>home</key>
+	<array><string>%s</string></array>
+	<key>name</key>
+	<array><string>%s</string><string>%s.alias</string></array>
+	<key>shell</key>
+	<array><string>%s</string></array>
+	<key>uid</key>
+	<array><string>%s</string></array>
+</dict>
+</plist>
+`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestPasswdUsers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passwd")
+	writeFile(t, path, testPasswd)
+
+	users, system, err := passwdUsers(path)
+	require.NoError(t, err)
+	assert.Equal(t, []User{
+		{Username: "alice", UID: "1000", Home: "/home/alice"},
+		{Username: "bob", UID: "1001", Home: "/srv/bob"},
+	}, users)
+	assert.Equal(t, []User{
+		{Username: "root", UID: "0", Home: "/root"},
+		{Username: "daemon", UID: "1", Home: "/usr/sbin"},
+		{Username: "systemd-network", UID: "998", Home: "/"},
+		{Username: "nobody", UID: "65534", Home: "/nonexistent"},
+		{Username: "svc", UID: "1002", Home: "/home/svc"},
+	}, system)
+}
+
+func TestPasswdUsersMissing(t *testing.T) {
+	users, system, err := passwdUsers(filepath.Join(t.TempDir(), "passwd"))
+	require.NoError(t, err)
+	assert.Empty(t, users)
+	assert.Empty(t, system)
+}
+
+func TestDSLocalUsers(t *testing.T) {
+	dir := t.TempDir()
+	record := func(home, name, shell, uid string) string {
+		return fmt.Sprintf(testDSLocalUser, home, name, name, shell, uid)
+	}
+	writeFile(t, filepath.Join(dir, "root.plist"), record("/var/root", "root", "/bin/sh", "0"))
+	writeFile(t, filepath.Join(dir, "_spotlight.plist"), record("/var/empty", "_spotlight", "/usr/bin/false", "89"))
+	writeFile(t, filepath.Join(dir, "alice.plist"), record("/Users/alice", "alice", "/bin/zsh", "501"))
+	writeFile(t, filepath.Join(dir, "_mbsetupuser.plist"), record("/var/setup", "_mbsetupuser", "/bin/bash", "248"))
+	writeFile(t, filepath.Join(dir, "corrupt.plist"), "not a plist")
+
+	users, system, err := dslocalUsers(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []User{{Username: "alice", UID: "501", Home: "/Users/alice"}}, users)
+	assert.Len(t, system, 3)
+}
+
+func TestUnlistedHomes(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"alice", "carol", "Shared", ".localized", "lost+found"} {
+		require.NoError(t, os.Mkdir(filepath.Join(root, name), 0755))
+	}
+	writeFile(t, filepath.Join(root, "notes.txt"), "")
+
+	accounts := []User{{Username: "alice", UID: "1000", Home: filepath.Join(root, "alice")}}
+	assert.Equal(t, []User{{Username: "carol", Home: filepath.Join(root, "carol")}}, unlistedHomes(root, accounts))
+	assert.Empty(t, unlistedHomes(filepath.Join(root, "missing"), nil))
+	assert.Empty(t, unlistedHomes("", nil))
+}
+
+func TestMockListerOptions(t *testing.T) {
+	lister := MockLister{Users: []User{
+		{Username: "alice", Home: "/home/alice"},
+		{Username: "bob", Home: "/home/bob"},
+		{Username: "carol", Home: "/home/carol"},
+	}}
+
+	users, err := lister.List()
+	require.NoError(t, err)
+	assert.Len(t, users, 3)
+
+	users, err = lister.List(WithUsername("bob"))
+	require.NoError(t, err)
+	assert.Equal(t, []User{{Username: "bob", Home: "/home/bob"}}, users)
+
+	queryContext := table.QueryContext{Constraints: map[string]table.ConstraintList{
+		"username": {Constraints: []table.Constraint{
+			{Operator: table.OperatorEquals, Expression: "alice"},
+			{Operator: table.OperatorEquals, Expression: "carol"},
+			{Operator: table.OperatorLike, Expression: "b%"},
+		}},
+	}}
+	users, err = lister.List(WithUsernames(queryContext))
+	require.NoError(t, err)
+	assert.Equal(t, []User{
+		{Username: "alice", Home: "/home/alice"},
+		{Username: "carol", Home: "/home/carol"},
+	}, users)
+
+	users, err = lister.List(WithUsernames(table.QueryContext{}))
+	require.NoError(t, err)
+	assert.Len(t, users, 3)
+}
+
+func TestOSListerHomes(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, "dave"), 0755))
+
+	originalRoots := homeDirRoots
+	originalPasswd, originalDSLocal := passwdPath, dslocalUsersDir
+	homeDirRoots = map[string]string{"linux": root, "darwin": root, "windows": root}
+	passwdPath = filepath.Join(root, "passwd")
+	dslocalUsersDir = filepath.Join(root, "dslocal")
+	t.Cleanup(func() {
+		homeDirRoots = originalRoots
+		passwdPath, dslocalUsersDir = originalPasswd, originalDSLocal
+	})
+
+	users, err := OSLister{}.List()
+	require.NoError(t, err)
+	assert.Equal(t, []User{{Username: "dave", Home: filepath.Join(root, "dave")}}, users)
+
+	users, err = OSLister{}.List(WithUsername("erin"))
+	require.NoError(t, err)
+	assert.Empty(t, users)
+}
+
+func TestOSListerSystemAccountHomes(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("passwd is only read on Linux")
+	}
+	root := t.TempDir()
+	for _, name := range []string{"alice", "syslog", "dave"} {
+		require.NoError(t, os.Mkdir(filepath.Join(root, name), 0755))
+	}
+	passwd := fmt.Sprintf("alice:x:1000:1000::%s:/bin/bash\nsyslog:x:104:110::%s:/usr/sbin/nologin\n",
+		filepath.Join(root, "alice"), filepath.Join(root, "syslog"))
+	writeFile(t, filepath.Join(root, "passwd"), passwd)
+
+	originalRoots, originalPasswd := homeDirRoots, passwdPath
+	homeDirRoots = map[string]string{"linux": root}
+	passwdPath = filepath.Join(root, "passwd")
+	t.Cleanup(func() {
+		homeDirRoots, passwdPath = originalRoots, originalPasswd
+	})
+
+	users, err := OSLister{}.List()
+	require.NoError(t, err)
+	assert.Equal(t, []User{
+		{Username: "alice", UID: "1000", Home: filepath.Join(root, "alice")},
+		{Username: "dave", Home: filepath.Join(root, "dave")},
+	}, users)
+}
@@ -0,0 +1,45 @@
+// Package userstest provides helpers for tests of tables that read files from
+// user home directories.
+package userstest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/macadmins/osquery-extension/pkg/users"
+)
+
+// SetMockUsers replaces the Lister at lister with a MockLister of accounts
+// until the test ends.
+func SetMockUsers(t testing.TB, lister *users.Lister, accounts ...users.User) {
+	t.Helper()
+	original := *lister
+	*lister = users.MockLister{Users: accounts}
+	t.Cleanup(func() { *lister = original })
+}
+
+// MockHome returns a user whose home is a temporary directory holding files.
+func MockHome(t testing.TB, username string, files map[string]string) users.User {
+	t.Helper()
+	home := filepath.Join(t.TempDir(), username)
+	WriteMockFiles(t, home, files)
+	return users.User{Username: username, Home: home}
+}
+
+// WriteMockFiles writes files, named relative to root with forward slashes.
+func WriteMockFiles(t testing.TB, root string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
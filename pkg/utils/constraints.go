@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+// constraintTimeLayouts are the formats accepted in time constraints.
+var constraintTimeLayouts = []string{
+	"2006-01-02 15:04:05 -0700",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// TimeLowerBound returns the latest lower bound placed on column by =, > or >=
+// constraints, or the zero time when there is none. Tables use it to stop
+// reading history older than the query can match.
+func TimeLowerBound(queryContext table.QueryContext, column string) (time.Time, error) {
+	var since time.Time
+	constraintList, present := queryContext.Constraints[column]
+	if !present {
+		return since, nil
+	}
+
+	for _, constraint := range constraintList.Constraints {
+		switch constraint.Operator {
+		case table.OperatorEquals, table.OperatorGreaterThan, table.OperatorGreaterThanOrEquals:
+		default:
+			continue
+		}
+		t, err := ParseConstraintTime(constraint.Expression)
+		if err != nil {
+			return since, err
+		}
+		if t.After(since) {
+			since = t
+		}
+	}
+
+	return since, nil
+}
+
+// ParseConstraintTime parses a time given in a constraint, either as a date
+// and time or as a unix time.
+func ParseConstraintTime(expression string) (time.Time, error) {
+	for _, layout := range constraintTimeLayouts {
+		if t, err := time.Parse(layout, expression); err == nil {
+			return t, nil
+		}
+	}
+	if epoch, err := strconv.ParseInt(expression, 10, 64); err == nil {
+		return time.Unix(epoch, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("unable to parse time constraint: %s", expression)
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeLowerBound(t *testing.T) {
+	queryContext := table.QueryContext{
+		Constraints: map[string]table.ConstraintList{
+			"start_time": {
+				Constraints: []table.Constraint{
+					{Operator: table.OperatorGreaterThanOrEquals, Expression: "2024-01-01 00:00:00 +0000"},
+					{Operator: table.OperatorGreaterThan, Expression: "1704153600"},
+					{Operator: table.OperatorLessThan, Expression: "2025-01-01"},
+				},
+			},
+		},
+	}
+	since, err := TimeLowerBound(queryContext, "start_time")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), since.UTC())
+
+	since, err = TimeLowerBound(queryContext, "time")
+	require.NoError(t, err)
+	assert.True(t, since.IsZero())
+
+	queryContext.Constraints["start_time"].Constraints[0].Expression = "yesterday"
+	_, err = TimeLowerBound(queryContext, "start_time")
+	assert.Error(t, err)
+}
+
+func TestParseConstraintTime(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       time.Time
+	}{
+		{"2024-01-14 06:00:00 +0100", time.Date(2024, 1, 14, 5, 0, 0, 0, time.UTC)},
+		{"2024-01-14T06:00:00Z", time.Date(2024, 1, 14, 6, 0, 0, 0, time.UTC)},
+		{"2024-01-14T06:00:00.123456789+00:00", time.Date(2024, 1, 14, 6, 0, 0, 123456789, time.UTC)},
+		{"2024-01-14 06:00:00", time.Date(2024, 1, 14, 6, 0, 0, 0, time.UTC)},
+		{"2024-01-14", time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)},
+		{"1705212000", time.Date(2024, 1, 14, 6, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			got, err := ParseConstraintTime(tt.expression)
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "got %s", got)
+		})
+	}
+
+	_, err := ParseConstraintTime("last week")
+	assert.Error(t, err)
+}
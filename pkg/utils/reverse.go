@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+)
+
+const defaultReverseChunkSize = 64 * 1024
+
+// ReverseLineReader reads the lines of a file from the last to the first, so
+// the most recent lines of a large log can be read without scanning it all.
+type ReverseLineReader struct {
+	r         io.ReaderAt
+	pos       int64
+	buf       []byte
+	done      bool
+	ChunkSize int
+	// MaxLineLength truncates longer lines to their first MaxLineLength
+	// bytes, bounding memory use. Zero means no limit.
+	MaxLineLength int
+}
+
+// NewReverseLineReader returns a reader for the first size bytes of r. A
+// trailing newline does not produce an empty last line.
+func NewReverseLineReader(r io.ReaderAt, size int64) (*ReverseLineReader, error) {
+	// an empty file has no lines
+	empty := size == 0
+	if size > 0 {
+		last := make([]byte, 1)
+		if _, err := r.ReadAt(last, size-1); err != nil {
+			return nil, err
+		}
+		if last[0] == '\n' {
+			size--
+		}
+	}
+	return &ReverseLineReader{r: r, pos: size, done: empty, ChunkSize: defaultReverseChunkSize}, nil
+}
+
+// ReadLine returns the previous line without its line ending, and the offset
+// of its first byte. io.EOF is returned once the start of the file is reached.
+func (rr *ReverseLineReader) ReadLine() (string, int64, error) {
+	for {
+		if i := bytes.LastIndexByte(rr.buf, '\n'); i >= 0 {
+			line := rr.buf[i+1:]
+			rr.buf = rr.buf[:i]
+			return rr.lineString(line), rr.pos + int64(i) + 1, nil
+		}
+		if rr.pos == 0 {
+			if rr.done {
+				return "", 0, io.EOF
+			}
+			rr.done = true
+			line := rr.buf
+			rr.buf = nil
+			return rr.lineString(line), 0, nil
+		}
+
+		n := int64(rr.ChunkSize)
+		if n > rr.pos {
+			n = rr.pos
+		}
+		chunk := make([]byte, n, n+int64(len(rr.buf)))
+		if _, err := rr.r.ReadAt(chunk, rr.pos-n); err != nil && err != io.EOF {
+			return "", 0, err
+		}
+		rr.pos -= n
+		rr.buf = append(chunk, rr.buf...)
+		// buf holds part of a single line, of which only the first
+		// MaxLineLength bytes are kept. The line starts in this chunk or an
+		// earlier one.
+		if rr.MaxLineLength > 0 && len(rr.buf) > int(n)+rr.MaxLineLength {
+			rr.buf = rr.buf[:int(n)+rr.MaxLineLength]
+		}
+	}
+}
+
+func (rr *ReverseLineReader) lineString(line []byte) string {
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if rr.MaxLineLength > 0 && len(line) > rr.MaxLineLength {
+		line = line[:rr.MaxLineLength]
+	}
+	return string(line)
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reverseLine struct {
+	Line   string
+	Offset int64
+}
+
+func readAllReverse(t *testing.T, content string, chunkSize int) []reverseLine {
+	t.Helper()
+	rr, err := NewReverseLineReader(strings.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+	rr.ChunkSize = chunkSize
+
+	var lines []reverseLine
+	for {
+		line, offset, err := rr.ReadLine()
+		if err == io.EOF {
+			return lines
+		}
+		require.NoError(t, err)
+		lines = append(lines, reverseLine{line, offset})
+	}
+}
+
+func TestReverseLineReader(t *testing.T) {
+	content := "first\r\nsecond line\n\nfourth\n"
+	expected := []reverseLine{
+		{"fourth", 20},
+		{"", 19},
+		{"second line", 7},
+		{"first", 0},
+	}
+
+	// chunk sizes smaller than, equal to and larger than the lines
+	for _, chunkSize := range []int{1, 3, 6, 1024} {
+		assert.Equal(t, expected, readAllReverse(t, content, chunkSize), "chunk size %d", chunkSize)
+	}
+}
+
+func TestReverseLineReaderNoTrailingNewline(t *testing.T) {
+	assert.Equal(t, []reverseLine{{"b", 2}, {"a", 0}}, readAllReverse(t, "a\nb", 2))
+}
+
+func TestReverseLineReaderEmpty(t *testing.T) {
+	assert.Empty(t, readAllReverse(t, "", 2))
+	assert.Equal(t, []reverseLine{{"", 0}}, readAllReverse(t, "\n", 2))
+}
+
+func TestReverseLineReaderMaxLineLength(t *testing.T) {
+	content := "short\n" + strings.Repeat("x", 50) + "y\nend\n"
+	for _, chunkSize := range []int{1, 4, 1024} {
+		rr, err := NewReverseLineReader(strings.NewReader(content), int64(len(content)))
+		require.NoError(t, err)
+		rr.ChunkSize = chunkSize
+		rr.MaxLineLength = 8
+
+		var lines []reverseLine
+		for {
+			line, offset, err := rr.ReadLine()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			lines = append(lines, reverseLine{line, offset})
+		}
+		assert.Equal(t, []reverseLine{
+			{"end", 58},
+			{"xxxxxxxx", 6},
+			{"short", 0},
+		}, lines, "chunk size %d", chunkSize)
+	}
+}
@@ -17,6 +17,15 @@ func BoolToString(b bool) string {
 	return "false"
 }
 
+// BoolToInt returns 1 for true, as osquery represents booleans in integer
+// columns.
+func BoolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 // FileSystem interface for os.Stat
 type FileSystem interface {
 	Stat(name string) (os.FileInfo, error)
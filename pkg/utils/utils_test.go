@@ -35,3 +35,8 @@ func TestBoolToString(t *testing.T) {
 	// Test that BoolToString returns "false" for false
 	assert.Equal(t, "false", BoolToString(false), "Expected false as string")
 }
+
+func TestBoolToInt(t *testing.T) {
+	assert.Equal(t, 1, BoolToInt(true))
+	assert.Equal(t, 0, BoolToInt(false))
+}
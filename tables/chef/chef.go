@@ -0,0 +1,90 @@
+package chef
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// chefReport is the run status written by Chef::Handler::JsonFile.
+type chefReport struct {
+	Node             chefNode       `json:"node"`
+	Success          bool           `json:"success"`
+	StartTime        string         `json:"start_time"`
+	EndTime          string         `json:"end_time"`
+	ElapsedTime      float64        `json:"elapsed_time"`
+	AllResources     []chefResource `json:"all_resources"`
+	UpdatedResources []chefResource `json:"updated_resources"`
+	Exception        string         `json:"exception"`
+	RunID            string         `json:"run_id"`
+}
+
+type chefNode struct {
+	Name            string   `json:"name"`
+	ChefEnvironment string   `json:"chef_environment"`
+	RunList         []string `json:"run_list"`
+}
+
+// chefResource is a resource serialized by Chef::Resource#to_json.
+type chefResource struct {
+	JSONClass    string `json:"json_class"`
+	InstanceVars struct {
+		Name         string          `json:"name"`
+		ResourceName string          `json:"resource_name"`
+		DeclaredType string          `json:"declared_type"`
+		Action       json.RawMessage `json:"action"`
+		CookbookName string          `json:"cookbook_name"`
+		RecipeName   string          `json:"recipe_name"`
+		SourceLine   string          `json:"source_line"`
+		ElapsedTime  float64         `json:"elapsed_time"`
+	} `json:"instance_vars"`
+}
+
+// reportsDir returns the directory Chef::Handler::JsonFile writes to, which
+// can be overridden with the CHEF_REPORTS_PATH env.
+func reportsDir() string {
+	if dir := os.Getenv("CHEF_REPORTS_PATH"); dir != "" {
+		return dir
+	}
+	if runtime.GOOS == "windows" {
+		return "C:\\chef\\reports"
+	}
+	return "/var/chef/reports"
+}
+
+// latestReportPath returns the most recent report in dir. Reports are named
+// chef-run-report-<YYYYMMDDHHMMSS>.json so the newest sorts last.
+func latestReportPath(dir string) (string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "chef-run-report-*.json"))
+	if err != nil {
+		return "", errors.Wrap(err, "list chef reports")
+	}
+	if len(paths) == 0 {
+		return "", nil
+	}
+	sort.Strings(paths)
+	return paths[len(paths)-1], nil
+}
+
+// loadLatestReport returns the most recent report and its path, or nil if
+// there is none.
+func loadLatestReport(dir string) (*chefReport, string, error) {
+	path, err := latestReportPath(dir)
+	if err != nil || path == "" {
+		return nil, "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "read chef report")
+	}
+	var report chefReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, "", errors.Wrapf(err, "unmarshal chef report %s", path)
+	}
+	return &report, path, nil
+}
@@ -0,0 +1,56 @@
+package chef
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+func ChefRunColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("node_name"),
+		table.TextColumn("environment"),
+		table.TextColumn("run_id"),
+		table.TextColumn("start_time"),
+		table.TextColumn("end_time"),
+		table.DoubleColumn("elapsed_time"),
+		table.TextColumn("success"),
+		table.TextColumn("exception"),
+		table.IntegerColumn("updated_resources"),
+		table.IntegerColumn("total_resources"),
+		table.TextColumn("run_list"),
+		table.TextColumn("path"),
+	}
+}
+
+func ChefRunGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	report, path, err := loadLatestReport(reportsDir())
+	if err != nil {
+		return nil, err
+	}
+	if report == nil {
+		return nil, nil
+	}
+
+	return []map[string]string{chefRunRow(report, path)}, nil
+}
+
+func chefRunRow(report *chefReport, path string) map[string]string {
+	return map[string]string{
+		"node_name":         report.Node.Name,
+		"environment":       report.Node.ChefEnvironment,
+		"run_id":            report.RunID,
+		"start_time":        report.StartTime,
+		"end_time":          report.EndTime,
+		"elapsed_time":      strconv.FormatFloat(report.ElapsedTime, 'f', -1, 64),
+		"success":           fmt.Sprintf("%v", report.Success),
+		"exception":         report.Exception,
+		"updated_resources": strconv.Itoa(len(report.UpdatedResources)),
+		"total_resources":   strconv.Itoa(len(report.AllResources)),
+		"run_list":          strings.Join(report.Node.RunList, ","),
+		"path":              path,
+	}
+}
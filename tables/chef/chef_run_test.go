@@ -0,0 +1,58 @@
+package chef
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChefRunGenerate(t *testing.T) {
+	dir := setupReports(t, map[string][]byte{
+		"chef-run-report-20240114120130.json": testChefRunReport,
+	})
+
+	rows, err := ChefRunGenerate(context.Background(), table.QueryContext{})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{
+			"node_name":         "web01.example.com",
+			"environment":       "production",
+			"run_id":            "6c9a5a3e-2f0c-4a55-a1a7-0d6b0b0d1c2e",
+			"start_time":        "2024-01-14 12:00:00 +0000",
+			"end_time":          "2024-01-14 12:01:30 +0000",
+			"elapsed_time":      "90.25",
+			"success":           "true",
+			"exception":         "",
+			"updated_resources": "2",
+			"total_resources":   "3",
+			"run_list":          "role[base],recipe[web::default]",
+			"path":              filepath.Join(dir, "chef-run-report-20240114120130.json"),
+		},
+	}, rows)
+}
+
+func TestChefRunGenerateFailed(t *testing.T) {
+	setupReports(t, map[string][]byte{
+		"chef-run-report-20240114120130.json": testChefRunReport,
+		"chef-run-report-20240115120005.json": testChefRunReportFailed,
+	})
+
+	rows, err := ChefRunGenerate(context.Background(), table.QueryContext{})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "false", rows[0]["success"])
+	assert.Equal(t, `Net::HTTPClientException: 403 "Forbidden"`, rows[0]["exception"])
+	assert.Equal(t, "0", rows[0]["updated_resources"])
+}
+
+func TestChefRunGenerateNoReports(t *testing.T) {
+	setupReports(t, nil)
+
+	rows, err := ChefRunGenerate(context.Background(), table.QueryContext{})
+	assert.NoError(t, err)
+	assert.Empty(t, rows)
+}
@@ -0,0 +1,64 @@
+package chef
+
+import (
+	_ "embed"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed test_chef_run_report.json
+var testChefRunReport []byte
+
+//go:embed test_chef_run_report_failed.json
+var testChefRunReportFailed []byte
+
+// setupReports writes the fixture reports to a temporary directory, pointed
+// to by CHEF_REPORTS_PATH, and returns it.
+func setupReports(t *testing.T, reports map[string][]byte) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range reports {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0600))
+	}
+	t.Setenv("CHEF_REPORTS_PATH", dir)
+	return dir
+}
+
+func TestReportsDir(t *testing.T) {
+	t.Setenv("CHEF_REPORTS_PATH", "")
+	assert.NotEmpty(t, reportsDir())
+
+	t.Setenv("CHEF_REPORTS_PATH", "/srv/chef/reports")
+	assert.Equal(t, "/srv/chef/reports", reportsDir())
+}
+
+func TestLoadLatestReport(t *testing.T) {
+	dir := setupReports(t, map[string][]byte{
+		"chef-run-report-20240114120130.json": testChefRunReport,
+		"chef-run-report-20240115120005.json": testChefRunReportFailed,
+		"other.json":                          []byte("not a report"),
+	})
+
+	report, path, err := loadLatestReport(dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "chef-run-report-20240115120005.json"), path)
+	assert.Equal(t, "a1d4a8a2-5b0e-4c1b-9f1e-3b4b0b6c7d8e", report.RunID)
+
+	report, path, err = loadLatestReport(t.TempDir())
+	assert.NoError(t, err)
+	assert.Nil(t, report)
+	assert.Empty(t, path)
+}
+
+func TestLoadLatestReportInvalid(t *testing.T) {
+	dir := setupReports(t, map[string][]byte{
+		"chef-run-report-20240114120130.json": []byte("{"),
+	})
+
+	_, _, err := loadLatestReport(dir)
+	assert.Error(t, err)
+}
@@ -0,0 +1,73 @@
+package chef
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+func ChefUpdatedResourcesColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("run_id"),
+		table.TextColumn("type"),
+		table.TextColumn("name"),
+		table.TextColumn("action"),
+		table.TextColumn("cookbook_name"),
+		table.TextColumn("recipe_name"),
+		table.TextColumn("source_line"),
+		table.DoubleColumn("elapsed_time"),
+		table.TextColumn("json_class"),
+	}
+}
+
+func ChefUpdatedResourcesGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	report, _, err := loadLatestReport(reportsDir())
+	if err != nil {
+		return nil, err
+	}
+	if report == nil {
+		return nil, nil
+	}
+
+	return buildUpdatedResourcesOutput(report), nil
+}
+
+func buildUpdatedResourcesOutput(report *chefReport) []map[string]string {
+	var results []map[string]string
+	for _, resource := range report.UpdatedResources {
+		vars := resource.InstanceVars
+		resourceType := vars.DeclaredType
+		if resourceType == "" {
+			resourceType = vars.ResourceName
+		}
+		results = append(results, map[string]string{
+			"run_id":        report.RunID,
+			"type":          resourceType,
+			"name":          vars.Name,
+			"action":        resourceAction(vars.Action),
+			"cookbook_name": vars.CookbookName,
+			"recipe_name":   vars.RecipeName,
+			"source_line":   vars.SourceLine,
+			"elapsed_time":  strconv.FormatFloat(vars.ElapsedTime, 'f', -1, 64),
+			"json_class":    resource.JSONClass,
+		})
+	}
+	return results
+}
+
+// resourceAction renders the action of a resource, which is serialized as a
+// single action or a list of them.
+func resourceAction(raw json.RawMessage) string {
+	var actions []string
+	if err := json.Unmarshal(raw, &actions); err == nil {
+		return strings.Join(actions, ",")
+	}
+	var action string
+	if err := json.Unmarshal(raw, &action); err == nil {
+		return action
+	}
+	return ""
+}
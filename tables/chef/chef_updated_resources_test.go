@@ -0,0 +1,58 @@
+package chef
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChefUpdatedResourcesGenerate(t *testing.T) {
+	setupReports(t, map[string][]byte{
+		"chef-run-report-20240114120130.json": testChefRunReport,
+	})
+
+	rows, err := ChefUpdatedResourcesGenerate(context.Background(), table.QueryContext{})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{
+			"run_id":        "6c9a5a3e-2f0c-4a55-a1a7-0d6b0b0d1c2e",
+			"type":          "package",
+			"name":          "nginx",
+			"action":        "install",
+			"cookbook_name": "web",
+			"recipe_name":   "default",
+			"source_line":   "/var/chef/cache/cookbooks/web/recipes/default.rb:1:in `from_file'",
+			"elapsed_time":  "12.5",
+			"json_class":    "Chef::Resource::Package",
+		},
+		{
+			"run_id":        "6c9a5a3e-2f0c-4a55-a1a7-0d6b0b0d1c2e",
+			"type":          "template",
+			"name":          "/etc/nginx/nginx.conf",
+			"action":        "create",
+			"cookbook_name": "web",
+			"recipe_name":   "default",
+			"source_line":   "",
+			"elapsed_time":  "0.04",
+			"json_class":    "Chef::Resource::Template",
+		},
+	}, rows)
+}
+
+func TestChefUpdatedResourcesGenerateNoReports(t *testing.T) {
+	setupReports(t, nil)
+
+	rows, err := ChefUpdatedResourcesGenerate(context.Background(), table.QueryContext{})
+	assert.NoError(t, err)
+	assert.Empty(t, rows)
+}
+
+func TestResourceAction(t *testing.T) {
+	assert.Equal(t, "enable,start", resourceAction(json.RawMessage(`["enable","start"]`)))
+	assert.Equal(t, "create", resourceAction(json.RawMessage(`"create"`)))
+	assert.Equal(t, "", resourceAction(nil))
+}
@@ -0,0 +1,229 @@
+package chromepolicies
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/micromdm/plist"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/pkg/errors"
+)
+
+const (
+	levelMandatory   = "mandatory"
+	levelRecommended = "recommended"
+	levelEnrollment  = "enrollment"
+
+	enrollmentRowName = "CloudManagementEnrollment"
+
+	// invalidDMToken is written in place of the DM token when the browser is
+	// removed from the Admin console.
+	invalidDMToken = "INVALID_DM_TOKEN"
+)
+
+// redactedPolicies are returned without their value as they carry
+// credentials.
+var redactedPolicies = map[string]bool{
+	"CloudManagementEnrollmentToken": true,
+}
+
+const redactedValue = `"<redacted>"`
+
+// policySource is a location Chrome reads platform policy from. Pattern may
+// be a glob. The policies of a PerUser source apply to the user named by the
+// directory of the file.
+type policySource struct {
+	Pattern string
+	Level   string
+	PerUser bool
+}
+
+var policySources = map[string][]policySource{
+	"linux": {
+		{Pattern: "/etc/opt/chrome/policies/managed/*.json", Level: levelMandatory},
+		{Pattern: "/etc/opt/chrome/policies/recommended/*.json", Level: levelRecommended},
+	},
+	"darwin": {
+		{Pattern: "/Library/Managed Preferences/com.google.Chrome.plist", Level: levelMandatory},
+		{Pattern: "/Library/Managed Preferences/*/com.google.Chrome.plist", Level: levelMandatory, PerUser: true},
+	},
+}
+
+// enrollmentFiles are where Chrome Browser Cloud Management keeps the token
+// used to enroll and the DM token it receives once enrolled.
+type enrollmentFiles struct {
+	EnrollmentToken string
+	DMToken         string
+}
+
+var enrollmentPaths = map[string]enrollmentFiles{
+	"linux": {
+		EnrollmentToken: "/etc/opt/chrome/policies/enrollment/CloudManagementEnrollmentToken",
+		DMToken:         "/etc/opt/chrome/policies/enrollment/CloudManagement",
+	},
+	"darwin": {
+		EnrollmentToken: "/Library/Google/Chrome/CloudManagementEnrollmentToken",
+		DMToken:         "/Library/Application Support/Google/Chrome Cloud Enrollment/CloudManagement",
+	},
+}
+
+func ChromePoliciesColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("name"),
+		table.TextColumn("value"),
+		table.TextColumn("level"),
+		table.TextColumn("source"),
+		table.TextColumn("username"),
+	}
+}
+
+func ChromePoliciesGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	results, err := policyRows(policySources[runtime.GOOS])
+	if err != nil {
+		return nil, err
+	}
+
+	if files, ok := enrollmentPaths[runtime.GOOS]; ok {
+		results = append(results, enrollmentRow(files, results))
+	}
+
+	return results, nil
+}
+
+// policyRows returns a row for every policy set in the sources, in the order
+// of the sources.
+func policyRows(sources []policySource) ([]map[string]string, error) {
+	var results []map[string]string
+	for _, source := range sources {
+		paths, err := filepath.Glob(source.Pattern)
+		if err != nil {
+			return nil, errors.Wrap(err, "list Chrome policy files")
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			policies, err := loadPolicies(path)
+			if err != nil {
+				// Chrome ignores files it cannot parse, and so do we
+				log.Printf("error reading Chrome policy file %s: %s", path, err)
+				continue
+			}
+
+			username := ""
+			if source.PerUser {
+				username = filepath.Base(filepath.Dir(path))
+			}
+
+			names := make([]string, 0, len(policies))
+			for name := range policies {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				value := policies[name]
+				if redactedPolicies[name] {
+					value = redactedValue
+				}
+				results = append(results, map[string]string{
+					"name":     name,
+					"value":    value,
+					"level":    source.Level,
+					"source":   path,
+					"username": username,
+				})
+			}
+		}
+	}
+	return results, nil
+}
+
+// loadPolicies reads a JSON or plist policy file into the JSON encoding of
+// each policy value.
+func loadPolicies(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read policy file")
+	}
+
+	policies := map[string]string{}
+	if strings.EqualFold(filepath.Ext(path), ".plist") {
+		var values map[string]interface{}
+		if err := plist.Unmarshal(data, &values); err != nil {
+			return nil, errors.Wrap(err, "decode policy plist")
+		}
+		for name, value := range values {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "encode policy %s", name)
+			}
+			policies[name] = string(encoded)
+		}
+		return policies, nil
+	}
+
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, errors.Wrap(err, "decode policy json")
+	}
+	for name, value := range values {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, value); err != nil {
+			return nil, errors.Wrapf(err, "encode policy %s", name)
+		}
+		policies[name] = compact.String()
+	}
+	return policies, nil
+}
+
+// enrollmentRow reports whether the browser is enrolled in Chrome Browser
+// Cloud Management. An enrollment token set through machine policy counts as
+// much as one in the token file.
+func enrollmentRow(files enrollmentFiles, policies []map[string]string) map[string]string {
+	status, source := "not_enrolled", ""
+
+	if token := readToken(files.EnrollmentToken); token != "" {
+		status, source = "pending", files.EnrollmentToken
+	} else {
+		for _, policy := range policies {
+			if policy["name"] == "CloudManagementEnrollmentToken" && policy["username"] == "" {
+				status, source = "pending", policy["source"]
+				break
+			}
+		}
+	}
+
+	switch readToken(files.DMToken) {
+	case "":
+	case invalidDMToken:
+		status, source = "invalid", files.DMToken
+	default:
+		status, source = "enrolled", files.DMToken
+	}
+
+	return map[string]string{
+		"name":     enrollmentRowName,
+		"value":    `"` + status + `"`,
+		"level":    levelEnrollment,
+		"source":   source,
+		"username": "",
+	}
+}
+
+func readToken(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("error reading Chrome enrollment file %s: %s", path, err)
+		}
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,212 @@
+package chromepolicies
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/macadmins/osquery-extension/pkg/users/userstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testManagedPreferences = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>BrowserSignin</key>
+	<integer>2</integer>
+	<key>CloudManagementEnrollmentToken</key>
+	<string>00000000-1111-2222-3333-444444444444</string>
+	<key>ExtensionInstallForcelist</key>
+	<array>
+		<string>cjpalhdlnbpafiamejdnhcphjbkeiagm</string>
+	</array>
+	<key>HomepageLocation</key>
+	<string>https://intranet.example.com</string>
+</dict>
+</plist>
+`
+
+func TestPolicyRowsJSON(t *testing.T) {
+	root := t.TempDir()
+	userstest.WriteMockFiles(t, root, map[string]string{
+		"managed/b_security.json": `{
+			"PasswordManagerEnabled": false,
+			"URLBlocklist": ["example.org", "example.net"]
+		}`,
+		"managed/a_homepage.json":   `{"HomepageLocation": "https://intranet.example.com"}`,
+		"managed/broken.json":       `{"HomepageLocation": `,
+		"managed/README":            "not a policy file",
+		"recommended/defaults.json": `{"ManagedBookmarks": [{"name": "Intranet", "url": "https://intranet.example.com"}]}`,
+	})
+
+	rows, err := policyRows([]policySource{
+		{Pattern: filepath.Join(root, "managed", "*.json"), Level: levelMandatory},
+		{Pattern: filepath.Join(root, "recommended", "*.json"), Level: levelRecommended},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{
+			"name":     "HomepageLocation",
+			"value":    `"https://intranet.example.com"`,
+			"level":    levelMandatory,
+			"source":   filepath.Join(root, "managed", "a_homepage.json"),
+			"username": "",
+		},
+		{
+			"name":     "PasswordManagerEnabled",
+			"value":    "false",
+			"level":    levelMandatory,
+			"source":   filepath.Join(root, "managed", "b_security.json"),
+			"username": "",
+		},
+		{
+			"name":     "URLBlocklist",
+			"value":    `["example.org","example.net"]`,
+			"level":    levelMandatory,
+			"source":   filepath.Join(root, "managed", "b_security.json"),
+			"username": "",
+		},
+		{
+			"name":     "ManagedBookmarks",
+			"value":    `[{"name":"Intranet","url":"https://intranet.example.com"}]`,
+			"level":    levelRecommended,
+			"source":   filepath.Join(root, "recommended", "defaults.json"),
+			"username": "",
+		},
+	}, rows)
+}
+
+func TestPolicyRowsPlist(t *testing.T) {
+	root := t.TempDir()
+	userstest.WriteMockFiles(t, root, map[string]string{
+		"com.google.Chrome.plist": testManagedPreferences,
+	})
+	source := filepath.Join(root, "com.google.Chrome.plist")
+
+	rows, err := policyRows([]policySource{{Pattern: source, Level: levelMandatory}})
+	require.NoError(t, err)
+
+	values := map[string]string{}
+	for _, row := range rows {
+		assert.Equal(t, levelMandatory, row["level"])
+		assert.Equal(t, source, row["source"])
+		assert.Equal(t, "", row["username"])
+		values[row["name"]] = row["value"]
+	}
+	assert.Equal(t, map[string]string{
+		"BrowserSignin":                  "2",
+		"CloudManagementEnrollmentToken": redactedValue,
+		"ExtensionInstallForcelist":      `["cjpalhdlnbpafiamejdnhcphjbkeiagm"]`,
+		"HomepageLocation":               `"https://intranet.example.com"`,
+	}, values)
+}
+
+func TestPolicyRowsPerUser(t *testing.T) {
+	root := t.TempDir()
+	userstest.WriteMockFiles(t, root, map[string]string{
+		"com.google.Chrome.plist":       testManagedPreferences,
+		"alice/com.google.Chrome.plist": testManagedPreferences,
+	})
+
+	rows, err := policyRows([]policySource{
+		{Pattern: filepath.Join(root, "com.google.Chrome.plist"), Level: levelMandatory},
+		{Pattern: filepath.Join(root, "*", "com.google.Chrome.plist"), Level: levelMandatory, PerUser: true},
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 8)
+
+	for _, row := range rows[:4] {
+		assert.Equal(t, "", row["username"])
+	}
+	for _, row := range rows[4:] {
+		assert.Equal(t, "alice", row["username"])
+		assert.Equal(t, filepath.Join(root, "alice", "com.google.Chrome.plist"), row["source"])
+	}
+
+	// only machine policy enrolls the browser
+	row := enrollmentRow(enrollmentFiles{}, rows[4:])
+	assert.Equal(t, `"not_enrolled"`, row["value"])
+}
+
+func TestPolicyRowsNoFiles(t *testing.T) {
+	rows, err := policyRows([]policySource{
+		{Pattern: filepath.Join(t.TempDir(), "managed", "*.json"), Level: levelMandatory},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+}
+
+func TestEnrollmentRow(t *testing.T) {
+	tests := []struct {
+		name           string
+		files          map[string]string
+		policies       []map[string]string
+		expectedStatus string
+		expectedSource string
+	}{
+		{
+			name:           "not enrolled",
+			expectedStatus: `"not_enrolled"`,
+		},
+		{
+			name:           "enrollment token file",
+			files:          map[string]string{"CloudManagementEnrollmentToken": "00000000-1111\n"},
+			expectedStatus: `"pending"`,
+			expectedSource: "CloudManagementEnrollmentToken",
+		},
+		{
+			name: "enrollment token policy",
+			policies: []map[string]string{
+				{"name": "CloudManagementEnrollmentToken", "source": "com.google.Chrome.plist"},
+			},
+			expectedStatus: `"pending"`,
+			expectedSource: "com.google.Chrome.plist",
+		},
+		{
+			name: "enrolled",
+			files: map[string]string{
+				"CloudManagementEnrollmentToken": "00000000-1111",
+				"CloudManagement":                "dm-token",
+			},
+			expectedStatus: `"enrolled"`,
+			expectedSource: "CloudManagement",
+		},
+		{
+			name:           "invalidated",
+			files:          map[string]string{"CloudManagement": invalidDMToken},
+			expectedStatus: `"invalid"`,
+			expectedSource: "CloudManagement",
+		},
+		{
+			name:           "empty dm token",
+			files:          map[string]string{"CloudManagement": ""},
+			expectedStatus: `"not_enrolled"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			userstest.WriteMockFiles(t, root, tt.files)
+			files := enrollmentFiles{
+				EnrollmentToken: filepath.Join(root, "CloudManagementEnrollmentToken"),
+				DMToken:         filepath.Join(root, "CloudManagement"),
+			}
+
+			expectedSource := tt.expectedSource
+			if _, ok := tt.files[expectedSource]; ok {
+				expectedSource = filepath.Join(root, expectedSource)
+			}
+
+			row := enrollmentRow(files, tt.policies)
+			assert.Equal(t, map[string]string{
+				"name":     enrollmentRowName,
+				"value":    tt.expectedStatus,
+				"level":    levelEnrollment,
+				"source":   expectedSource,
+				"username": "",
+			}, row)
+		})
+	}
+}
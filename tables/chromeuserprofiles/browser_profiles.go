@@ -0,0 +1,113 @@
+package chromeuserprofiles
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+
+	"github.com/macadmins/osquery-extension/pkg/users"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+// chromiumBrowser is a Chromium-derived browser, which keeps its profiles in
+// the info_cache of a Local State file below each user's home.
+type chromiumBrowser struct {
+	Name           string
+	LocalStateDirs map[string][]string
+}
+
+var chromiumBrowsers = []chromiumBrowser{
+	{
+		Name: "Google Chrome",
+		LocalStateDirs: map[string][]string{
+			"windows": {"Appdata/Local/Google/Chrome/User Data"},
+			"darwin":  {"Library/Application Support/Google/Chrome"},
+			"linux":   {".config/google-chrome"},
+		},
+	},
+	{
+		Name: "Chromium",
+		LocalStateDirs: map[string][]string{
+			"windows": {"AppData/Local/Chromium/User Data"},
+			"darwin":  {"Library/Application Support/Chromium"},
+			"linux":   {".config/chromium", "snap/chromium/current/.config/chromium"},
+		},
+	},
+	{
+		Name: "Microsoft Edge",
+		LocalStateDirs: map[string][]string{
+			"windows": {"AppData/Local/Microsoft/Edge/User Data"},
+			"darwin":  {"Library/Application Support/Microsoft Edge"},
+			"linux":   {".config/microsoft-edge"},
+		},
+	},
+	{
+		Name: "Brave",
+		LocalStateDirs: map[string][]string{
+			"windows": {"AppData/Local/BraveSoftware/Brave-Browser/User Data"},
+			"darwin":  {"Library/Application Support/BraveSoftware/Brave-Browser"},
+			"linux":   {".config/BraveSoftware/Brave-Browser", "snap/brave/current/.config/BraveSoftware/Brave-Browser"},
+		},
+	},
+	{
+		Name: "Vivaldi",
+		LocalStateDirs: map[string][]string{
+			"windows": {"AppData/Local/Vivaldi/User Data"},
+			"darwin":  {"Library/Application Support/Vivaldi"},
+			"linux":   {".config/vivaldi"},
+		},
+	},
+	{
+		Name: "Arc",
+		LocalStateDirs: map[string][]string{
+			"windows": {"AppData/Local/Packages/TheBrowserCompany.Arc_*/LocalCache/Local/Arc/User Data"},
+			"darwin":  {"Library/Application Support/Arc/User Data"},
+		},
+	},
+}
+
+// googleChromeBrowsers returns the browsers reported by google_chrome_profiles.
+// On Linux it has always included Chromium.
+func googleChromeBrowsers() []chromiumBrowser {
+	var browsers []chromiumBrowser
+	for _, browser := range chromiumBrowsers {
+		if browser.Name == "Google Chrome" || (runtime.GOOS == "linux" && browser.Name == "Chromium") {
+			browsers = append(browsers, browser)
+		}
+	}
+	return browsers
+}
+
+func BrowserProfilesColumns() []table.ColumnDefinition {
+	return append([]table.ColumnDefinition{table.TextColumn("browser")}, GoogleChromeProfilesColumns()...)
+}
+
+func BrowserProfilesGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	return generateForBrowsers(ctx, queryContext, chromiumBrowsers), nil
+}
+
+// generateForBrowsers returns the profiles of every user of the browsers, or
+// of the users the query constrains username to. Rows carry the browser name,
+// which google_chrome_profiles drops.
+func generateForBrowsers(ctx context.Context, queryContext table.QueryContext, browsers []chromiumBrowser) []map[string]string {
+	var results []map[string]string
+	for _, browser := range browsers {
+		for _, localStateDir := range browser.LocalStateDirs[runtime.GOOS] {
+			userFiles, err := findFileInUserDirs(filepath.Join(localStateDir, "Local State"), users.WithUsernames(queryContext))
+			if err != nil {
+				continue
+			}
+			for _, file := range userFiles {
+				res, err := generateForPath(ctx, file)
+				if err != nil {
+					continue
+				}
+				for _, row := range res {
+					row["browser"] = browser.Name
+				}
+				results = append(results, res...)
+			}
+		}
+	}
+	return results
+}
@@ -0,0 +1,83 @@
+package chromeuserprofiles
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/macadmins/osquery-extension/pkg/users"
+	"github.com/macadmins/osquery-extension/pkg/users/userstest"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testLocalState = `{"profile": {"info_cache": {"Default": {"name": "Work", "user_name": "user@example.com"}}}}`
+
+// setupBrowserHomes writes a Local State file for each browser into a
+// temporary home of testuser, and returns testuser.
+func setupBrowserHomes(t *testing.T, browsers ...string) users.User {
+	t.Helper()
+	files := map[string]string{}
+	for _, browser := range chromiumBrowsers {
+		for _, name := range browsers {
+			if browser.Name != name {
+				continue
+			}
+			dir := filepath.ToSlash(browser.LocalStateDirs[runtime.GOOS][0])
+			files[dir+"/Local State"] = testLocalState
+			files[dir+"/Default/Preferences"] = "{}"
+		}
+	}
+
+	user := userstest.MockHome(t, "testuser", files)
+	userstest.SetMockUsers(t, &userLister, user)
+
+	return user
+}
+
+func TestBrowserProfilesColumns(t *testing.T) {
+	columns := BrowserProfilesColumns()
+	assert.Equal(t, "browser", columns[0].Name)
+	assert.Len(t, columns, len(GoogleChromeProfilesColumns())+1)
+}
+
+func TestBrowserProfilesGenerate(t *testing.T) {
+	setupBrowserHomes(t, "Google Chrome", "Microsoft Edge", "Brave")
+
+	rows, err := BrowserProfilesGenerate(context.Background(), table.QueryContext{})
+	require.NoError(t, err)
+
+	var browsers []string
+	for _, row := range rows {
+		browsers = append(browsers, row["browser"])
+		assert.Equal(t, "testuser", row["username"])
+		assert.Equal(t, "user@example.com", row["email"])
+	}
+	assert.Equal(t, []string{"Google Chrome", "Microsoft Edge", "Brave"}, browsers)
+}
+
+func TestGoogleChromeProfilesGenerate(t *testing.T) {
+	setupBrowserHomes(t, "Google Chrome", "Microsoft Edge")
+
+	rows, err := GoogleChromeProfilesGenerate(context.Background(), table.QueryContext{})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "Work", rows[0]["name"])
+	assert.NotContains(t, rows[0], "browser")
+}
+
+func TestBrowserProfilesGenerateUsername(t *testing.T) {
+	user := setupBrowserHomes(t, "Google Chrome")
+	userstest.SetMockUsers(t, &userLister, user, users.User{Username: "otheruser", Home: user.Home})
+
+	rows, err := BrowserProfilesGenerate(context.Background(), table.QueryContext{
+		Constraints: map[string]table.ConstraintList{
+			"username": {Constraints: []table.Constraint{{Operator: table.OperatorEquals, Expression: "otheruser"}}},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "otheruser", rows[0]["username"])
+}
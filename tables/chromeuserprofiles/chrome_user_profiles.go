@@ -5,46 +5,24 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strconv"
 
+	"github.com/macadmins/osquery-extension/pkg/users"
+	"github.com/macadmins/osquery-extension/pkg/utils"
 	"github.com/osquery/osquery-go/plugin/table"
 	"github.com/pkg/errors"
 )
 
 // Very much inspired (i.e. mostly copied, but I'm having problems importing it) by https://github.com/kolide/launcher/blob/master/pkg/osquery/table/chrome_user_profiles.go
 
-type findFile struct {
-	username string
-}
-
-type FindFileOpt func(*findFile)
-
-func WithUsername(username string) FindFileOpt {
-	return func(ff *findFile) {
-		ff.username = username
-	}
-}
-
-var homeDirLocations = map[string][]string{
-	"windows": {"/Users"}, // windows10 uses /Users
-	"darwin":  {"/Users"},
-	"linux":   {"/home"},
-}
+// userLister lists the users whose homes are searched for browser profiles.
+var userLister users.Lister = users.OSLister{}
 
 type userFileInfo struct {
 	user string
 	path string
 }
 
-var chromeLocalStateDirs = map[string][]string{
-	"windows": []string{"Appdata/Local/Google/Chrome/User Data"},
-	"darwin":  []string{"Library/Application Support/Google/Chrome"},
-}
-
-// try the list of known linux paths if runtime.GOOS doesn't match 'darwin' or 'windows'
-var chromeLocalStateDirDefault = []string{".config/google-chrome", ".config/chromium", "snap/chromium/current/.config/chromium"}
-
 type chromeLocalState struct {
 	Profile struct {
 		InfoCache map[string]chromeProfileInfo `json:"info_cache"`
@@ -55,6 +33,18 @@ type chromeProfileInfo struct {
 	Name      string `json:"name"`
 	Ephemeral bool   `json:"is_ephemeral"`
 	Email     string `json:"user_name"`
+	GaiaID    string `json:"gaia_id"`
+	// HostedDomain is NO_HOSTED_DOMAIN for consumer Google accounts
+	HostedDomain string `json:"hosted_domain"`
+	// ActiveTime is the unix time the profile was last used, in fractional
+	// seconds
+	ActiveTime                    float64 `json:"active_time"`
+	UsingDefaultName              bool    `json:"is_using_default_name"`
+	ConsentedPrimaryAccount       bool    `json:"is_consented_primary_account"`
+	SupervisedUserID              string  `json:"managed_user_id"`
+	UserAcceptedAccountManagement bool    `json:"user_accepted_account_management"`
+	SigninRequired                bool    `json:"signin_required"`
+	SigninWithCredentialProvider  bool    `json:"signin.with_credential_provider"`
 }
 
 func GoogleChromeProfilesColumns() []table.ColumnDefinition {
@@ -64,6 +54,15 @@ func GoogleChromeProfilesColumns() []table.ColumnDefinition {
 		table.TextColumn("name"),
 		table.IntegerColumn("ephemeral"),
 		table.TextColumn("path"),
+		table.TextColumn("gaia_id"),
+		table.TextColumn("hosted_domain"),
+		table.BigIntColumn("active_time"),
+		table.IntegerColumn("is_using_default_name"),
+		table.IntegerColumn("is_consented_primary_account"),
+		table.IntegerColumn("is_supervised"),
+		table.IntegerColumn("is_managed"),
+		table.IntegerColumn("signin_required"),
+		table.IntegerColumn("signin_with_credential_provider"),
 	}
 }
 
@@ -88,12 +87,26 @@ func generateForPath(ctx context.Context, fileInfo userFileInfo) ([]map[string]s
 			return nil, errors.Wrap(err, "checking profile path exists")
 		}
 
+		activeTime := ""
+		if profileInfo.ActiveTime > 0 {
+			activeTime = strconv.FormatInt(int64(profileInfo.ActiveTime), 10)
+		}
+
 		results = append(results, map[string]string{
-			"username":  fileInfo.user,
-			"email":     profileInfo.Email,
-			"name":      profileInfo.Name,
-			"ephemeral": strconv.Itoa(btoi(profileInfo.Ephemeral)),
-			"path":      profilePath,
+			"username":                        fileInfo.user,
+			"email":                           profileInfo.Email,
+			"name":                            profileInfo.Name,
+			"ephemeral":                       strconv.Itoa(utils.BoolToInt(profileInfo.Ephemeral)),
+			"path":                            profilePath,
+			"gaia_id":                         profileInfo.GaiaID,
+			"hosted_domain":                   profileInfo.HostedDomain,
+			"active_time":                     activeTime,
+			"is_using_default_name":           strconv.Itoa(utils.BoolToInt(profileInfo.UsingDefaultName)),
+			"is_consented_primary_account":    strconv.Itoa(utils.BoolToInt(profileInfo.ConsentedPrimaryAccount)),
+			"is_supervised":                   strconv.Itoa(utils.BoolToInt(profileInfo.SupervisedUserID != "")),
+			"is_managed":                      strconv.Itoa(utils.BoolToInt(profileInfo.UserAcceptedAccountManagement)),
+			"signin_required":                 strconv.Itoa(utils.BoolToInt(profileInfo.SigninRequired)),
+			"signin_with_credential_provider": strconv.Itoa(utils.BoolToInt(profileInfo.SigninWithCredentialProvider)),
 		})
 	}
 
@@ -112,84 +125,31 @@ func profilePathStat(localStatePath, profileDir string) (string, error) {
 }
 
 func GoogleChromeProfilesGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	osChromeLocalStateDirs, ok := chromeLocalStateDirs[runtime.GOOS]
-	if !ok {
-		osChromeLocalStateDirs = chromeLocalStateDirDefault
-	}
-
-	var results []map[string]string
-	for _, localStateFilePath := range osChromeLocalStateDirs {
-		userFiles, err := findFileInUserDirs(filepath.Join(localStateFilePath, "Local State"))
-		if err != nil {
-			continue
-		}
-		for _, file := range userFiles {
-			res, err := generateForPath(ctx, file)
-			if err != nil {
-				continue
-			}
-			results = append(results, res...)
-		}
+	results := generateForBrowsers(ctx, queryContext, googleChromeBrowsers())
+	for _, row := range results {
+		delete(row, "browser")
 	}
 	return results, nil
 }
 
-func findFileInUserDirs(pattern string, opts ...FindFileOpt) ([]userFileInfo, error) {
-	ff := &findFile{}
-
-	for _, opt := range opts {
-		opt(ff)
-	}
-
-	homedirRoots, ok := homeDirLocations[runtime.GOOS]
-	if !ok {
-		return []userFileInfo{}, errors.New("No homedir location found for this GOOS")
+// findFileInUserDirs returns the regular files matching pattern, relative to
+// the home of each user.
+func findFileInUserDirs(pattern string, opts ...users.Option) ([]userFileInfo, error) {
+	accounts, err := userLister.List(opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "list users")
 	}
 
 	foundPaths := []userFileInfo{}
-
-	// Redo/remove when we make username a required parameter
-	if ff.username == "" {
-		for _, possibleHome := range homedirRoots {
-
-			userDirs, err := os.ReadDir(possibleHome)
-			if err != nil {
-				// This possibleHome doesn't exist. Move on
-				continue
-			}
-
-			// For each user's dir, in this possibleHome, check!
-			for _, ud := range userDirs {
-				userPathPattern := filepath.Join(possibleHome, ud.Name(), pattern)
-				fullPaths, err := filepath.Glob(userPathPattern)
-				if err != nil {
-					continue
-				}
-				for _, fullPath := range fullPaths {
-					if stat, err := os.Stat(fullPath); err == nil && stat.Mode().IsRegular() {
-						foundPaths = append(foundPaths, userFileInfo{
-							user: ud.Name(),
-							path: fullPath,
-						})
-					}
-				}
-			}
-		}
-
-		return foundPaths, nil
-	}
-
-	// We have a username. Future normal path here
-	for _, possibleHome := range homedirRoots {
-		userPathPattern := filepath.Join(possibleHome, ff.username, pattern)
-		fullPaths, err := filepath.Glob(userPathPattern)
+	for _, account := range accounts {
+		fullPaths, err := filepath.Glob(filepath.Join(account.Home, pattern))
 		if err != nil {
 			continue
 		}
 		for _, fullPath := range fullPaths {
 			if stat, err := os.Stat(fullPath); err == nil && stat.Mode().IsRegular() {
 				foundPaths = append(foundPaths, userFileInfo{
-					user: ff.username,
+					user: account.Username,
 					path: fullPath,
 				})
 			}
@@ -197,10 +157,3 @@ func findFileInUserDirs(pattern string, opts ...FindFileOpt) ([]userFileInfo, er
 	}
 	return foundPaths, nil
 }
-
-func btoi(value bool) int {
-	if value {
-		return 1
-	}
-	return 0
-}
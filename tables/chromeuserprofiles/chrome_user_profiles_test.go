@@ -4,22 +4,23 @@ import (
 	"context"
 	"os"
 	"path/filepath"
-	"runtime"
 	"testing"
 
+	"github.com/macadmins/osquery-extension/pkg/users"
+	"github.com/macadmins/osquery-extension/pkg/users/userstest"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestBtoi(t *testing.T) {
-	assert.Equal(t, 1, btoi(true))
-	assert.Equal(t, 0, btoi(false))
-}
-
 func TestGoogleChromeProfilesColumns(t *testing.T) {
 	columns := GoogleChromeProfilesColumns()
-	assert.Len(t, columns, 5)
+	assert.Len(t, columns, 14)
 
-	expectedColumnNames := []string{"username", "email", "name", "ephemeral", "path"}
+	expectedColumnNames := []string{
+		"username", "email", "name", "ephemeral", "path",
+		"gaia_id", "hosted_domain", "active_time", "is_using_default_name",
+		"is_consented_primary_account", "is_supervised", "is_managed",
+		"signin_required", "signin_with_credential_provider",
+	}
 	for i, column := range columns {
 		assert.Equal(t, expectedColumnNames[i], column.Name)
 	}
@@ -39,16 +40,21 @@ func TestFindFileInUserDirs(t *testing.T) {
 	err = os.WriteFile(testFile, []byte("test data"), os.ModePerm)
 	assert.NoError(t, err)
 
-	// Set the home directory location for the current platform
-	homeDirLocations[runtime.GOOS] = []string{tempDir}
+	// Only testuser's home is searched
+	userstest.SetMockUsers(t, &userLister, users.User{Username: "testuser", Home: userDir}, users.User{Username: "otheruser", Home: tempDir})
 
 	// Test with a username
-	foundFiles, err := findFileInUserDirs("testfile.txt", WithUsername("testuser"))
+	foundFiles, err := findFileInUserDirs("testfile.txt", users.WithUsername("testuser"))
 	assert.NoError(t, err)
 	assert.Len(t, foundFiles, 1)
 	assert.Equal(t, "testuser", foundFiles[0].user)
 	assert.Equal(t, testFile, foundFiles[0].path)
 
+	// Test with a user that has no such file
+	foundFiles, err = findFileInUserDirs("testfile.txt", users.WithUsername("otheruser"))
+	assert.NoError(t, err)
+	assert.Empty(t, foundFiles)
+
 	// Test without a username
 	foundFiles, err = findFileInUserDirs("testfile.txt")
 	assert.NoError(t, err)
@@ -75,12 +81,22 @@ func TestGenerateForPath(t *testing.T) {
 				"profile1": {
 					"name": "Profile 1",
 					"is_ephemeral": false,
-					"user_name": "profile1@example.com"
+					"user_name": "profile1@example.com",
+					"gaia_id": "109876543210987654321",
+					"hosted_domain": "example.com",
+					"active_time": 1705233600.123456,
+					"is_using_default_name": false,
+					"is_consented_primary_account": true,
+					"user_accepted_account_management": true
 				},
 				"profile2": {
 					"name": "Profile 2",
 					"is_ephemeral": true,
-					"user_name": "profile2@example.com"
+					"user_name": "profile2@example.com",
+					"hosted_domain": "NO_HOSTED_DOMAIN",
+					"is_using_default_name": true,
+					"managed_user_id": "ChildAccountSUID",
+					"signin_required": true
 				}
 			}
 		}
@@ -101,11 +117,20 @@ func TestGenerateForPath(t *testing.T) {
 
 	expectedProfiles := []map[string]string{
 		{
-			"username":  "testuser",
-			"email":     "profile1@example.com",
-			"name":      "Profile 1",
-			"ephemeral": "0",
-			"path":      profile1Path,
+			"username":                        "testuser",
+			"email":                           "profile1@example.com",
+			"name":                            "Profile 1",
+			"ephemeral":                       "0",
+			"path":                            profile1Path,
+			"gaia_id":                         "109876543210987654321",
+			"hosted_domain":                   "example.com",
+			"active_time":                     "1705233600",
+			"is_using_default_name":           "0",
+			"is_consented_primary_account":    "1",
+			"is_supervised":                   "0",
+			"is_managed":                      "1",
+			"signin_required":                 "0",
+			"signin_with_credential_provider": "0",
 		},
 		{
 			"username":  "testuser",
@@ -113,7 +138,16 @@ func TestGenerateForPath(t *testing.T) {
 			"name":      "Profile 2",
 			"ephemeral": "1",
 			// this profile directory doesn't exist, so the path should be blank
-			"path": "",
+			"path":                            "",
+			"gaia_id":                         "",
+			"hosted_domain":                   "NO_HOSTED_DOMAIN",
+			"active_time":                     "",
+			"is_using_default_name":           "1",
+			"is_consented_primary_account":    "0",
+			"is_supervised":                   "1",
+			"is_managed":                      "0",
+			"signin_required":                 "1",
+			"signin_with_credential_provider": "0",
 		},
 	}
 
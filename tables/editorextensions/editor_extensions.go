@@ -0,0 +1,215 @@
+package editorextensions
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/macadmins/osquery-extension/pkg/users"
+	"github.com/macadmins/osquery-extension/pkg/utils"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/pkg/errors"
+)
+
+// userLister lists the users whose homes are searched for extensions.
+var userLister users.Lister = users.OSLister{}
+
+// editor is a VS Code derived editor and where it installs extensions,
+// relative to each home.
+type editor struct {
+	Name          string
+	ExtensionsDir string
+}
+
+var editors = []editor{
+	{Name: "Visual Studio Code", ExtensionsDir: ".vscode/extensions"},
+	{Name: "Visual Studio Code - Insiders", ExtensionsDir: ".vscode-insiders/extensions"},
+	{Name: "Cursor", ExtensionsDir: ".cursor/extensions"},
+	{Name: "VSCodium", ExtensionsDir: ".vscode-oss/extensions"},
+}
+
+// installedExtension is an entry of the extensions.json the editor keeps in
+// its extensions directory.
+type installedExtension struct {
+	Identifier struct {
+		ID string `json:"id"`
+	} `json:"identifier"`
+	Version  string `json:"version"`
+	Location struct {
+		FSPath string `json:"fsPath"`
+		Path   string `json:"path"`
+	} `json:"location"`
+	RelativeLocation string `json:"relativeLocation"`
+	Metadata         struct {
+		InstalledTimestamp  int64 `json:"installedTimestamp"`
+		IsPreReleaseVersion bool  `json:"isPreReleaseVersion"`
+	} `json:"metadata"`
+}
+
+// extensionManifest is the package.json of an extension.
+type extensionManifest struct {
+	Name             string   `json:"name"`
+	DisplayName      string   `json:"displayName"`
+	Publisher        string   `json:"publisher"`
+	Version          string   `json:"version"`
+	ActivationEvents []string `json:"activationEvents"`
+}
+
+func EditorExtensionsColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("username"),
+		table.TextColumn("editor"),
+		table.TextColumn("identifier"),
+		table.TextColumn("name"),
+		table.TextColumn("version"),
+		table.TextColumn("publisher"),
+		table.BigIntColumn("installed_time"),
+		table.IntegerColumn("pre_release"),
+		table.TextColumn("activation_events"),
+		table.TextColumn("path"),
+	}
+}
+
+func EditorExtensionsGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	accounts, err := userLister.List(users.WithUsernames(queryContext))
+	if err != nil {
+		return nil, errors.Wrap(err, "list users")
+	}
+
+	var results []map[string]string
+	for _, account := range accounts {
+		for _, editor := range editors {
+			rows, err := extensionRows(filepath.Join(account.Home, filepath.FromSlash(editor.ExtensionsDir)))
+			if err != nil {
+				log.Printf("error reading %s extensions of %s: %s", editor.Name, account.Username, err)
+				continue
+			}
+			for _, row := range rows {
+				row["username"] = account.Username
+				row["editor"] = editor.Name
+			}
+			results = append(results, rows...)
+		}
+	}
+
+	return results, nil
+}
+
+// extensionRows returns a row for each extension installed in dir. Editors
+// list their extensions in extensions.json; older ones did not, in which case
+// every extension directory that is not marked obsolete is read.
+func extensionRows(dir string) ([]map[string]string, error) {
+	extensions, err := loadInstalledExtensions(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []map[string]string
+	for _, extension := range extensions {
+		path := extension.Location.FSPath
+		if extension.RelativeLocation != "" {
+			path = filepath.Join(dir, extension.RelativeLocation)
+		} else if path == "" {
+			path = extension.Location.Path
+		}
+
+		var manifest extensionManifest
+		if err := loadJSON(filepath.Join(path, "package.json"), &manifest); err != nil && !os.IsNotExist(errors.Cause(err)) {
+			log.Printf("error reading extension manifest in %s: %s", path, err)
+		}
+
+		identifier := extension.Identifier.ID
+		if identifier == "" && manifest.Publisher != "" && manifest.Name != "" {
+			identifier = manifest.Publisher + "." + manifest.Name
+		}
+		version := extension.Version
+		if version == "" {
+			version = manifest.Version
+		}
+		publisher := manifest.Publisher
+		if publisher == "" {
+			publisher, _, _ = strings.Cut(identifier, ".")
+		}
+
+		installedTime := ""
+		if extension.Metadata.InstalledTimestamp > 0 {
+			// milliseconds since the epoch
+			installedTime = strconv.FormatInt(extension.Metadata.InstalledTimestamp/1000, 10)
+		}
+
+		activationEvents := ""
+		if manifest.ActivationEvents != nil {
+			encoded, err := json.Marshal(manifest.ActivationEvents)
+			if err != nil {
+				return nil, errors.Wrap(err, "encode activation events")
+			}
+			activationEvents = string(encoded)
+		}
+
+		results = append(results, map[string]string{
+			"identifier":        identifier,
+			"name":              manifest.DisplayName,
+			"version":           version,
+			"publisher":         publisher,
+			"installed_time":    installedTime,
+			"pre_release":       strconv.Itoa(utils.BoolToInt(extension.Metadata.IsPreReleaseVersion)),
+			"activation_events": activationEvents,
+			"path":              path,
+		})
+	}
+
+	return results, nil
+}
+
+// loadInstalledExtensions reads extensions.json in dir, or lists the extension
+// directories when there is none. A missing dir has no extensions.
+func loadInstalledExtensions(dir string) ([]installedExtension, error) {
+	var extensions []installedExtension
+	err := loadJSON(filepath.Join(dir, "extensions.json"), &extensions)
+	if err == nil {
+		return extensions, nil
+	}
+	if !os.IsNotExist(errors.Cause(err)) {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "list extensions")
+	}
+
+	// .obsolete holds the extensions that were uninstalled or updated but not
+	// yet removed, keyed by directory name
+	obsolete := map[string]bool{}
+	if err := loadJSON(filepath.Join(dir, ".obsolete"), &obsolete); err != nil && !os.IsNotExist(errors.Cause(err)) {
+		log.Printf("error reading obsolete extensions in %s: %s", dir, err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || obsolete[entry.Name()] {
+			continue
+		}
+		var extension installedExtension
+		extension.RelativeLocation = entry.Name()
+		extensions = append(extensions, extension)
+	}
+	return extensions, nil
+}
+
+func loadJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "read %s", filepath.Base(path))
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return errors.Wrapf(err, "decode %s", filepath.Base(path))
+	}
+	return nil
+}
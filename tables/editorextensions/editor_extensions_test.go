@@ -0,0 +1,117 @@
+package editorextensions
+
+import (
+	"context"
+	_ "embed"
+	"path/filepath"
+	"testing"
+
+	"github.com/macadmins/osquery-extension/pkg/users/userstest"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed test_extensions.json
+var testExtensions []byte
+
+const testPythonManifest = `{
+  "name": "python",
+  "displayName": "Python",
+  "publisher": "ms-python",
+  "version": "2024.2.1",
+  "activationEvents": ["onLanguage:python", "workspaceContains:**/*.py"]
+}`
+
+func TestExtensionRows(t *testing.T) {
+	dir := t.TempDir()
+	userstest.WriteMockFiles(t, dir, map[string]string{
+		"extensions.json":                            string(testExtensions),
+		"ms-python.python-2024.2.1/package.json":     testPythonManifest,
+		"github.copilot-chat-0.13.2024011501/README": "",
+	})
+
+	rows, err := extensionRows(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{
+			"identifier":        "ms-python.python",
+			"name":              "Python",
+			"version":           "2024.2.1",
+			"publisher":         "ms-python",
+			"installed_time":    "1705233600",
+			"pre_release":       "0",
+			"activation_events": `["onLanguage:python","workspaceContains:**/*.py"]`,
+			"path":              filepath.Join(dir, "ms-python.python-2024.2.1"),
+		},
+		{
+			// no package.json, so only what extensions.json knows
+			"identifier":        "github.copilot-chat",
+			"name":              "",
+			"version":           "0.13.2024011501",
+			"publisher":         "github",
+			"installed_time":    "1705320000",
+			"pre_release":       "1",
+			"activation_events": "",
+			"path":              filepath.Join(dir, "github.copilot-chat-0.13.2024011501"),
+		},
+	}, rows)
+}
+
+func TestExtensionRowsWithoutExtensionsJSON(t *testing.T) {
+	dir := t.TempDir()
+	userstest.WriteMockFiles(t, dir, map[string]string{
+		"ms-python.python-2024.2.1/package.json": testPythonManifest,
+		"ms-python.python-2024.0.0/package.json": `{"name": "python", "publisher": "ms-python", "version": "2024.0.0"}`,
+		".obsolete":                              `{"ms-python.python-2024.0.0": true}`,
+	})
+
+	rows, err := extensionRows(dir)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "ms-python.python", rows[0]["identifier"])
+	assert.Equal(t, "2024.2.1", rows[0]["version"])
+	assert.Equal(t, "", rows[0]["installed_time"])
+}
+
+func TestExtensionRowsMissing(t *testing.T) {
+	rows, err := extensionRows(filepath.Join(t.TempDir(), ".vscode", "extensions"))
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+}
+
+func TestExtensionRowsCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	userstest.WriteMockFiles(t, dir, map[string]string{"extensions.json": "[{"})
+
+	_, err := extensionRows(dir)
+	assert.Error(t, err)
+}
+
+func TestEditorExtensionsGenerate(t *testing.T) {
+	userstest.SetMockUsers(t, &userLister,
+		userstest.MockHome(t, "testuser", map[string]string{
+			".vscode/extensions/ms-python.python-2024.2.1/package.json": testPythonManifest,
+			".cursor/extensions/extensions.json":                        string(testExtensions),
+		}),
+		userstest.MockHome(t, "otheruser", nil),
+	)
+
+	rows, err := EditorExtensionsGenerate(context.Background(), table.QueryContext{})
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, "Visual Studio Code", rows[0]["editor"])
+	assert.Equal(t, "Cursor", rows[1]["editor"])
+	assert.Equal(t, "Cursor", rows[2]["editor"])
+	for _, row := range rows {
+		assert.Equal(t, "testuser", row["username"])
+	}
+
+	rows, err = EditorExtensionsGenerate(context.Background(), table.QueryContext{
+		Constraints: map[string]table.ConstraintList{
+			"username": {Constraints: []table.Constraint{{Operator: table.OperatorEquals, Expression: "otheruser"}}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+}
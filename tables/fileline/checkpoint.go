@@ -0,0 +1,255 @@
+package fileline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/macadmins/osquery-extension/pkg/utils"
+)
+
+// checkpointEntry is how far a file was read for a checkpoint. For a
+// compressed file, Offset is into its decompressed content and EOF is set
+// once it was read to its end, as it does not grow.
+type checkpointEntry struct {
+	Inode  uint64 `json:"inode"`
+	Size   int64  `json:"size"`
+	Offset int64  `json:"offset"`
+	Lines  int64  `json:"lines"`
+	EOF    bool   `json:"eof,omitempty"`
+}
+
+// checkpointState holds the entries of every checkpoint, by checkpoint name
+// then path.
+type checkpointState map[string]map[string]checkpointEntry
+
+// checkpointMu serializes queries using checkpoints, which all share the
+// state file.
+var checkpointMu sync.Mutex
+
+// checkpointStatePath returns where checkpoints are kept. FILE_LINES_STATE_PATH
+// overrides the default.
+func checkpointStatePath() string {
+	if path := os.Getenv("FILE_LINES_STATE_PATH"); path != "" {
+		return path
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		return "C:\\ProgramData\\macadmins_extension\\file_lines_checkpoints.json"
+	case "darwin":
+		return "/Library/Application Support/macadmins_extension/file_lines_checkpoints.json"
+	default:
+		return "/var/lib/macadmins_extension/file_lines_checkpoints.json"
+	}
+}
+
+// loadCheckpoints reads the state file. A missing file has no checkpoints.
+func loadCheckpoints(path string) (checkpointState, error) {
+	state := checkpointState{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("read checkpoints: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("decode checkpoints: %w", err)
+	}
+	return state, nil
+}
+
+// save replaces the state file, so an interrupted write does not lose every
+// checkpoint. The entries of files that no longer exist, even rotated, are
+// dropped.
+func (s checkpointState) save(path string) error {
+	for name, entries := range s {
+		for file, entry := range entries {
+			if _, err := os.Stat(file); os.IsNotExist(err) && rotatedFileWithInode(file, entry.Inode) == "" {
+				delete(entries, file)
+			}
+		}
+		if len(entries) == 0 {
+			delete(s, name)
+		}
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("encode checkpoints: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create checkpoint directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("create checkpoints: %w", err)
+	}
+	defer func() {
+		// only left behind if the rename did not happen
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write checkpoints: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write checkpoints: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace checkpoints: %w", err)
+	}
+	return nil
+}
+
+// readFromCheckpoint reads the lines appended to the file at path since
+// opts.Checkpoint was last updated for it, and updates it. If the file was
+// rotated, the lines appended to it before are read from the rotated file
+// first. If it was truncated, it is read from the start.
+func readFromCheckpoint(path string, fs utils.FileSystem, opts readOptions) ([]FileLine, error) {
+	if !utils.FileExists(fs, path) {
+		err := errors.New("file does not exist")
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			fmt.Printf("error closing file: %s\n", err)
+		}
+	}()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	inode := fileInode(info)
+	previous, seen := opts.Checkpoint[path]
+
+	opts.Last = 0
+	opts.From = 0
+
+	if isCompressed(path) {
+		// compressed files do not grow, so are read to their end once
+		from := position{}
+		if seen && previous.Inode == inode && previous.Size == info.Size() {
+			if previous.EOF {
+				return nil, nil
+			}
+			from = position{Offset: previous.Offset, LineNumber: previous.Lines}
+		}
+		output, end, err := readCompressedFrom(file, path, opts, from)
+		if err != nil && !errors.Is(err, errMaxLines) {
+			return output, err
+		}
+		opts.Checkpoint[path] = checkpointEntry{
+			Inode:  inode,
+			Size:   info.Size(),
+			Offset: end.Offset,
+			Lines:  end.LineNumber,
+			EOF:    err == nil,
+		}
+		return output, err
+	}
+
+	opts.CompleteLinesOnly = true
+	var output []FileLine
+	from := position{}
+	switch {
+	case !seen:
+	case previous.Inode == inode && info.Size() >= previous.Offset:
+		from = position{Offset: previous.Offset, LineNumber: previous.Lines}
+	case previous.Inode != inode:
+		if rotated := rotatedFileWithInode(path, previous.Inode); rotated != "" {
+			lines, end, err := readRotatedFrom(rotated, opts, position{Offset: previous.Offset, LineNumber: previous.Lines})
+			if err != nil && !errors.Is(err, errMaxLines) {
+				return nil, err
+			}
+			output = append(output, lines...)
+			opts.MaxLines -= len(lines)
+			if err != nil || opts.MaxLines <= 0 {
+				// the rest of the rotated file, and the file, are read next time
+				previous.Offset, previous.Lines = end.Offset, end.LineNumber
+				opts.Checkpoint[path] = previous
+				if err == nil && info.Size() == 0 {
+					return output, nil
+				}
+				return output, errMaxLines
+			}
+		}
+	}
+	// otherwise the file was truncated, and is read from the start
+
+	// lines appended while reading are left to the next query
+	reader := io.NewSectionReader(file, from.Offset, info.Size()-from.Offset)
+	lines, end, err := readForwardFrom(reader, path, opts, from)
+	output = append(output, lines...)
+	if err != nil && !errors.Is(err, errMaxLines) {
+		return output, err
+	}
+	opts.Checkpoint[path] = checkpointEntry{Inode: inode, Size: info.Size(), Offset: end.Offset, Lines: end.LineNumber}
+	return output, err
+}
+
+// rotatedFileWithInode returns the uncompressed file path was rotated to that
+// has the inode, if any.
+func rotatedFileWithInode(path string, inode uint64) string {
+	if inode == 0 {
+		return ""
+	}
+	for _, rotated := range rotatedFiles(path) {
+		if isCompressed(rotated) {
+			continue
+		}
+		info, err := os.Stat(rotated)
+		if err == nil && fileInode(info) == inode {
+			return rotated
+		}
+	}
+	return ""
+}
+
+// readRotatedFrom reads the rest of a rotated file from the position the file
+// had reached before it was rotated.
+func readRotatedFrom(path string, opts readOptions, from position) ([]FileLine, position, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, from, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			fmt.Printf("error closing file: %s\n", err)
+		}
+	}()
+	if _, err := file.Seek(from.Offset, io.SeekStart); err != nil {
+		return nil, from, err
+	}
+	// the file is no longer written to, so its last line is complete
+	opts.CompleteLinesOnly = false
+	return readForwardFrom(file, path, opts, from)
+}
+
+// readCompressedFrom reads a compressed file from a position in its
+// decompressed content.
+func readCompressedFrom(file io.Reader, path string, opts readOptions, from position) ([]FileLine, position, error) {
+	reader, err := decompress(file, path)
+	if err != nil {
+		return nil, from, err
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			fmt.Printf("error closing decompressed reader: %s\n", err)
+		}
+	}()
+	if _, err := io.CopyN(io.Discard, reader, from.Offset); err != nil {
+		return nil, from, err
+	}
+	return readForwardFrom(reader, path, opts, from)
+}
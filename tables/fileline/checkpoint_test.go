@@ -0,0 +1,252 @@
+package fileline
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/macadmins/osquery-extension/pkg/utils"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkpointReader reads a file with a checkpoint kept in a temporary state
+// file, returning the lines read each time. capped is whether the last read
+// reached max_lines.
+type checkpointReader struct {
+	t         *testing.T
+	path      string
+	statePath string
+	opts      readOptions
+	capped    bool
+}
+
+func newCheckpointReader(t *testing.T, path string) *checkpointReader {
+	return &checkpointReader{
+		t:         t,
+		path:      path,
+		statePath: filepath.Join(t.TempDir(), "state", "checkpoints.json"),
+		opts:      defaultReadOptions(),
+	}
+}
+
+func (r *checkpointReader) read() []string {
+	r.t.Helper()
+	fs := utils.MockFileSystem{FileExists: true, Err: nil}
+	output, err := processFileWithCheckpoint(r.path, false, fs, r.opts, "test", r.statePath)
+	require.NoError(r.t, err)
+	var lines []string
+	r.capped = false
+	for _, line := range output {
+		if line.Error == errMaxLines.Error() {
+			r.capped = true
+			continue
+		}
+		require.Empty(r.t, line.Error)
+		lines = append(lines, line.Line)
+	}
+	return lines
+}
+
+func appendFile(t *testing.T, path, content string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+}
+
+func TestCheckpointAppended(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "line1\nline2\n")
+	r := newCheckpointReader(t, path)
+
+	assert.Equal(t, []string{"line1", "line2"}, r.read())
+	assert.Empty(t, r.read())
+
+	// a line still being written is left for the next query
+	appendFile(t, path, "line3\nline4 partial")
+	assert.Equal(t, []string{"line3"}, r.read())
+	appendFile(t, path, " done\n")
+	assert.Equal(t, []string{"line4 partial done"}, r.read())
+
+	state, err := loadCheckpoints(r.statePath)
+	require.NoError(t, err)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, checkpointEntry{
+		Inode:  fileInode(info),
+		Size:   info.Size(),
+		Offset: info.Size(),
+		Lines:  4,
+	}, state["test"][path])
+}
+
+func TestCheckpointLineNumbers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "line1\nline2\n")
+	r := newCheckpointReader(t, path)
+	r.read()
+
+	appendFile(t, path, "line3\n")
+	fs := utils.MockFileSystem{FileExists: true, Err: nil}
+	output, err := processFileWithCheckpoint(path, false, fs, r.opts, "test", r.statePath)
+	require.NoError(t, err)
+	assert.Equal(t, []FileLine{{Path: path, Line: "line3", LineNumber: 3, ByteOffset: 12}}, output)
+}
+
+func TestCheckpointMaxLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "line1\nline2\nline3\n")
+	r := newCheckpointReader(t, path)
+	r.opts.MaxLines = 2
+
+	assert.Equal(t, []string{"line1", "line2"}, r.read())
+	assert.True(t, r.capped)
+	assert.Equal(t, []string{"line3"}, r.read())
+	assert.False(t, r.capped)
+}
+
+func TestCheckpointTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "line1\nline2\n")
+	r := newCheckpointReader(t, path)
+	r.read()
+
+	require.NoError(t, os.WriteFile(path, []byte("new1\n"), 0644))
+	assert.Equal(t, []string{"new1"}, r.read())
+}
+
+func TestCheckpointRotated(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("rotation is detected by inode")
+	}
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "line1\n")
+	r := newCheckpointReader(t, path)
+	r.read()
+
+	// more is logged, then the log is rotated and a longer one started
+	appendFile(t, path, "line2\n")
+	require.NoError(t, os.Rename(path, path+".1"))
+	appendFile(t, path, "new1\nnew2\nnew3\n")
+
+	assert.Equal(t, []string{"line2", "new1", "new2", "new3"}, r.read())
+	assert.Empty(t, r.read())
+}
+
+func TestCheckpointRotatedMaxLines(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("rotation is detected by inode")
+	}
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "line1\n")
+	r := newCheckpointReader(t, path)
+	r.read()
+
+	appendFile(t, path, "line2\nline3\n")
+	require.NoError(t, os.Rename(path, path+".1"))
+	appendFile(t, path, "new1\n")
+
+	r.opts.MaxLines = 1
+	assert.Equal(t, []string{"line2"}, r.read())
+	assert.Equal(t, []string{"line3"}, r.read())
+	assert.Equal(t, []string{"new1"}, r.read())
+}
+
+func TestCheckpointCompressed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	require.NoError(t, os.WriteFile(path, []byte(gzipped(t, "gz1\ngz2")), 0644))
+	r := newCheckpointReader(t, path)
+
+	assert.Equal(t, []string{"gz1", "gz2"}, r.read())
+	assert.Empty(t, r.read())
+}
+
+func TestCheckpointCompressedMaxLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	require.NoError(t, os.WriteFile(path, []byte(gzipped(t, "gz1\ngz2\ngz3\n")), 0644))
+	r := newCheckpointReader(t, path)
+	r.opts.MaxLines = 2
+
+	assert.Equal(t, []string{"gz1", "gz2"}, r.read())
+	assert.True(t, r.capped)
+	assert.Equal(t, []string{"gz3"}, r.read())
+	assert.False(t, r.capped)
+	assert.Empty(t, r.read())
+}
+
+func TestCheckpointPruned(t *testing.T) {
+	dir := t.TempDir()
+	kept, removed := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	appendFile(t, kept, "line1\n")
+	appendFile(t, removed, "line1\n")
+	statePath := filepath.Join(t.TempDir(), "checkpoints.json")
+	fs := utils.MockFileSystem{FileExists: true, Err: nil}
+
+	_, err := processFileWithCheckpoint(filepath.Join(dir, "%.log"), true, fs, defaultReadOptions(), "test", statePath)
+	require.NoError(t, err)
+	_, err = processFileWithCheckpoint(kept, false, fs, defaultReadOptions(), "other", statePath)
+	require.NoError(t, err)
+
+	require.NoError(t, os.Remove(removed))
+	_, err = processFileWithCheckpoint(kept, false, fs, defaultReadOptions(), "test", statePath)
+	require.NoError(t, err)
+
+	state, err := loadCheckpoints(statePath)
+	require.NoError(t, err)
+	assert.Len(t, state, 2)
+	assert.Contains(t, state["test"], kept)
+	assert.NotContains(t, state["test"], removed)
+
+	// a checkpoint left with no files is dropped
+	require.NoError(t, os.Remove(kept))
+	_, err = processFileWithCheckpoint(kept, false, fs, defaultReadOptions(), "test", statePath)
+	require.NoError(t, err)
+	state, err = loadCheckpoints(statePath)
+	require.NoError(t, err)
+	assert.Empty(t, state)
+}
+
+func TestCheckpointsSeparate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "line1\n")
+	statePath := filepath.Join(t.TempDir(), "checkpoints.json")
+	fs := utils.MockFileSystem{FileExists: true, Err: nil}
+
+	for _, checkpoint := range []string{"a", "b"} {
+		output, err := processFileWithCheckpoint(path, false, fs, defaultReadOptions(), checkpoint, statePath)
+		require.NoError(t, err)
+		assert.Len(t, output, 1, checkpoint)
+	}
+}
+
+func TestLoadCheckpointsCorrupt(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "checkpoints.json")
+	require.NoError(t, os.WriteFile(statePath, []byte("{"), 0600))
+
+	_, err := loadCheckpoints(statePath)
+	assert.Error(t, err)
+}
+
+func TestFileLineGenerateCheckpoint(t *testing.T) {
+	path := writeTestFile(t, "line1\n")
+	t.Setenv("FILE_LINES_STATE_PATH", filepath.Join(t.TempDir(), "checkpoints.json"))
+	queryContext := table.QueryContext{Constraints: map[string]table.ConstraintList{
+		"path":       {Constraints: []table.Constraint{{Operator: table.OperatorEquals, Expression: path}}},
+		"checkpoint": {Constraints: []table.Constraint{{Operator: table.OperatorEquals, Expression: "shipper"}}},
+	}}
+
+	rows, err := FileLineGenerate(context.Background(), queryContext)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "shipper", rows[0]["checkpoint"])
+
+	rows, err = FileLineGenerate(context.Background(), queryContext)
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+}
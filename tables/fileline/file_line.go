@@ -2,26 +2,94 @@ package fileline
 
 import (
 	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/macadmins/osquery-extension/pkg/utils"
 	"github.com/osquery/osquery-go/plugin/table"
 )
 
+const (
+	// defaultMaxLines caps the lines returned by a query that does not set
+	// max_lines, so reading a large file cannot exhaust memory.
+	defaultMaxLines = 100000
+	// defaultMaxLineLength is the length lines are truncated to when the
+	// query does not set max_line_length.
+	defaultMaxLineLength = 1024 * 1024
+)
+
+// errMaxLines is returned with the lines read when max_lines was reached
+// before every line was, so that the result is not taken to be complete.
+var errMaxLines = errors.New("max_lines reached, remaining lines not read")
+
 type FileLine struct {
 	Line string
 	Path string
+	// LineNumber is the 1-based number of the line, or 0 when the file was
+	// read from the end or from an offset and it is not known.
+	LineNumber int64
+	ByteOffset int64
+	// Error is set, and Line and LineNumber are not, when the file could not
+	// be read. ByteOffset is then where reading started, or for errMaxLines
+	// that of the last line returned, so the next page starts after it.
+	Error string
+}
+
+// readOptions are the limits a query places on reading files.
+type readOptions struct {
+	// Last reads only the last lines of each file, if not 0.
+	Last          int
+	MaxLines      int
+	MaxLineLength int
+	// KeepLast keeps the last lines of a file rather than its first when
+	// MaxLines leaves some out.
+	KeepLast bool
+	// Pattern, if set, skips the lines it does not match.
+	Pattern        *regexp.Regexp
+	IncludeRotated bool
+	// Checkpoint, if set, holds where each file was last read up to. Only
+	// the lines appended since are read, and it is updated as files are read.
+	Checkpoint map[string]checkpointEntry
+	// CompleteLinesOnly leaves a last line without a line ending unread.
+	CompleteLinesOnly bool
+	// From skips the lines that start before this byte offset, so that a
+	// file larger than MaxLines can be read a page at a time.
+	From int64
+}
+
+func (o readOptions) match(line string) bool {
+	return o.Pattern == nil || o.Pattern.MatchString(line)
+}
+
+func defaultReadOptions() readOptions {
+	return readOptions{MaxLines: defaultMaxLines, MaxLineLength: defaultMaxLineLength}
 }
 
 func FileLineColumns() []table.ColumnDefinition {
 	return []table.ColumnDefinition{
 		table.TextColumn("line"),
 		table.TextColumn("path"),
+		table.BigIntColumn("line_number"),
+		table.BigIntColumn("byte_offset"),
+		table.IntegerColumn("last"),
+		table.IntegerColumn("max_lines"),
+		table.IntegerColumn("max_line_length"),
+		table.TextColumn("pattern"),
+		table.IntegerColumn("include_rotated"),
+		table.TextColumn("error"),
+		table.TextColumn("checkpoint"),
 	}
 }
 
@@ -45,51 +113,347 @@ func FileLineGenerate(ctx context.Context, queryContext table.QueryContext) ([]m
 			}
 		}
 	}
+
+	opts := defaultReadOptions()
+	// the limits are echoed back, or osquery would filter out every row
+	limits := map[string]string{"last": "", "max_lines": "", "max_line_length": ""}
+	for column, value := range map[string]*int{
+		"last":            &opts.Last,
+		"max_lines":       &opts.MaxLines,
+		"max_line_length": &opts.MaxLineLength,
+	} {
+		expression, n, err := intConstraint(queryContext, column)
+		if err != nil {
+			return nil, err
+		}
+		if expression != "" {
+			*value = n
+			limits[column] = expression
+		}
+	}
+
+	pattern := ""
+	if constraintList, present := queryContext.Constraints["pattern"]; present {
+		for _, constraint := range constraintList.Constraints {
+			if constraint.Operator == table.OperatorEquals {
+				pattern = constraint.Expression
+			}
+		}
+	}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		opts.Pattern = re
+	}
+
+	from, err := offsetConstraint(queryContext, "byte_offset")
+	if err != nil {
+		return nil, err
+	}
+	opts.From = from
+
+	includeRotated := ""
+	if constraintList, present := queryContext.Constraints["include_rotated"]; present {
+		for _, constraint := range constraintList.Constraints {
+			if constraint.Operator == table.OperatorEquals {
+				includeRotated = constraint.Expression
+			}
+		}
+	}
+	opts.IncludeRotated = includeRotated == "1"
+
+	checkpoint := ""
+	if constraintList, present := queryContext.Constraints["checkpoint"]; present {
+		for _, constraint := range constraintList.Constraints {
+			if constraint.Operator == table.OperatorEquals {
+				checkpoint = constraint.Expression
+			}
+		}
+	}
+
 	var results []map[string]string
 	fs := utils.OSFileSystem{}
-	output, err := processFile(path, wildcard, fs)
+	var output []FileLine
+	if checkpoint != "" {
+		output, err = processFileWithCheckpoint(path, wildcard, fs, opts, checkpoint, checkpointStatePath())
+	} else {
+		output, err = processFile(path, wildcard, fs, opts)
+	}
 	if err != nil {
 		return results, err
 	}
 
 	for _, item := range output {
+		lineNumber := ""
+		if item.LineNumber > 0 {
+			lineNumber = strconv.FormatInt(item.LineNumber, 10)
+		}
 		results = append(results, map[string]string{
-			"line": item.Line,
-			"path": item.Path,
+			"line":            item.Line,
+			"path":            item.Path,
+			"line_number":     lineNumber,
+			"byte_offset":     strconv.FormatInt(item.ByteOffset, 10),
+			"last":            limits["last"],
+			"max_lines":       limits["max_lines"],
+			"max_line_length": limits["max_line_length"],
+			"pattern":         pattern,
+			"include_rotated": includeRotated,
+			"error":           item.Error,
+			"checkpoint":      checkpoint,
 		})
 	}
 
 	return results, nil
 }
 
-func processFile(path string, wildcard bool, fs utils.FileSystem) ([]FileLine, error) {
+// intConstraint returns the expression and value of an = constraint on a
+// column that takes a positive number.
+func intConstraint(queryContext table.QueryContext, column string) (string, int, error) {
+	constraintList, present := queryContext.Constraints[column]
+	if !present {
+		return "", 0, nil
+	}
+	for _, constraint := range constraintList.Constraints {
+		if constraint.Operator != table.OperatorEquals {
+			continue
+		}
+		n, err := strconv.Atoi(constraint.Expression)
+		if err != nil || n < 1 {
+			return "", 0, fmt.Errorf("%s must be a positive number: %s", column, constraint.Expression)
+		}
+		return constraint.Expression, n, nil
+	}
+	return "", 0, nil
+}
+
+// offsetConstraint returns the offset the >, >= and = constraints on a column
+// holding byte offsets start at, or 0 if there are none.
+func offsetConstraint(queryContext table.QueryContext, column string) (int64, error) {
+	constraintList, present := queryContext.Constraints[column]
+	if !present {
+		return 0, nil
+	}
+	var from int64
+	for _, constraint := range constraintList.Constraints {
+		switch constraint.Operator {
+		case table.OperatorGreaterThan, table.OperatorGreaterThanOrEquals, table.OperatorEquals:
+		default:
+			continue
+		}
+		n, err := strconv.ParseInt(constraint.Expression, 10, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%s must be a number that is not negative: %s", column, constraint.Expression)
+		}
+		if constraint.Operator == table.OperatorGreaterThan {
+			n++
+		}
+		from = max(from, n)
+	}
+	return from, nil
+}
+
+func processFile(path string, wildcard bool, fs utils.FileSystem, opts readOptions) ([]FileLine, error) {
 
 	var output []FileLine
 
+	if path == "" {
+		return output, nil
+	}
+
+	files := []string{path}
 	if wildcard {
 		replacedPath := strings.ReplaceAll(path, "%", "*")
 
-		files, err := filepath.Glob(replacedPath)
+		var err error
+		files, err = filepath.Glob(replacedPath)
 		if err != nil {
 			return nil, err
 		}
-		for _, file := range files {
-			lines, _ := readLines(file, fs)
-			output = append(output, lines...)
+	}
 
+	// each file is read with its rotated siblings, newest first; checkpoints
+	// follow a file through rotation by themselves
+	var groups [][]string
+	seen := map[string]bool{}
+	for _, file := range files {
+		group := []string{file}
+		if opts.IncludeRotated && opts.Checkpoint == nil {
+			group = append(group, rotatedFiles(file)...)
+		}
+		var unseen []string
+		for _, sibling := range group {
+			if !seen[sibling] {
+				seen[sibling] = true
+				unseen = append(unseen, sibling)
+			}
+		}
+		if len(unseen) > 0 {
+			groups = append(groups, unseen)
 		}
-	} else {
-		lines, _ := readLines(path, fs)
-		output = append(output, lines...)
+	}
+
+	read := readLines
+	if opts.Checkpoint != nil {
+		read = readFromCheckpoint
+	}
+
+	count := 0
+	for _, group := range groups {
+		// reading newest first makes max_lines leave out the oldest lines of
+		// a group, which is still returned oldest first
+		var groupOutput []FileLine
+		capped := ""
+		for _, file := range group {
+			if count >= opts.MaxLines {
+				// the files left are not read
+				capped = file
+				break
+			}
+			fileOpts := opts
+			fileOpts.MaxLines -= count
+			fileOpts.KeepLast = len(group) > 1
+			lines, err := read(file, fs, fileOpts)
+			count += len(lines)
+			if errors.Is(err, errMaxLines) {
+				capped = file
+			} else if err != nil {
+				lines = append(lines, FileLine{Path: file, ByteOffset: opts.From, Error: err.Error()})
+			}
+			groupOutput = append(lines, groupOutput...)
+			if capped != "" {
+				break
+			}
+		}
+
+		if capped == "" {
+			output = append(output, groupOutput...)
+			continue
+		}
+		marker := FileLine{Path: capped, ByteOffset: opts.From, Error: errMaxLines.Error()}
+		if len(group) > 1 {
+			// the lines left out are older than those read
+			output = append(append(output, marker), groupOutput...)
+		} else {
+			if opts.Last == 0 && len(groupOutput) > 0 {
+				// the next page starts after the last line returned
+				marker.ByteOffset = groupOutput[len(groupOutput)-1].ByteOffset
+			}
+			output = append(append(output, groupOutput...), marker)
+		}
+		break
 	}
 
 	return output, nil
 
 }
 
-func readLines(path string, fs utils.FileSystem) ([]FileLine, error) {
-	var output []FileLine
+// processFileWithCheckpoint reads only the lines appended to the files since
+// the last query with the same checkpoint, keeping checkpoints in statePath.
+func processFileWithCheckpoint(path string, wildcard bool, fs utils.FileSystem, opts readOptions, checkpoint, statePath string) ([]FileLine, error) {
+	checkpointMu.Lock()
+	defer checkpointMu.Unlock()
+
+	state, err := loadCheckpoints(statePath)
+	if err != nil {
+		return nil, err
+	}
+	opts.Checkpoint = state[checkpoint]
+	if opts.Checkpoint == nil {
+		opts.Checkpoint = map[string]checkpointEntry{}
+	}
+
+	output, err := processFile(path, wildcard, fs, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	state[checkpoint] = opts.Checkpoint
+	if err := state.save(statePath); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// rotatedSibling is a file another was rotated to. Numbered siblings have a
+// generation, others, such as dated ones, are named by suffix.
+type rotatedSibling struct {
+	path       string
+	modTime    time.Time
+	generation int
+	suffix     string
+}
 
+// rotatedFiles returns the siblings path was rotated to, such as path.1,
+// path.2.gz, path.3.bz2 or path.20240101.gz, newest first. Rotation keeps
+// the modification time of a file, so they are ordered by it, then by
+// generation or dated suffix.
+func rotatedFiles(path string) []string {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil
+	}
+
+	var siblings []rotatedSibling
+	for _, entry := range entries {
+		suffix, found := strings.CutPrefix(entry.Name(), filepath.Base(path)+".")
+		if !found || entry.IsDir() {
+			continue
+		}
+		compressed := false
+		for _, ext := range []string{".gz", ".bz2"} {
+			if strings.HasSuffix(suffix, ext) {
+				suffix = strings.TrimSuffix(suffix, ext)
+				compressed = true
+			}
+		}
+		n, err := strconv.Atoi(suffix)
+		if err != nil && !compressed {
+			// not a rotated log, such as path.lock
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		sibling := rotatedSibling{
+			path:       filepath.Join(filepath.Dir(path), entry.Name()),
+			modTime:    info.ModTime(),
+			generation: -1,
+			suffix:     suffix,
+		}
+		// numbers as long as a date, such as 20240101, are dates
+		if err == nil && len(suffix) < len("20060102") {
+			sibling.generation = n
+		}
+		siblings = append(siblings, sibling)
+	}
+
+	sort.SliceStable(siblings, func(i, j int) bool {
+		a, b := siblings[i], siblings[j]
+		switch {
+		case !a.modTime.Equal(b.modTime):
+			return a.modTime.After(b.modTime)
+		case a.generation >= 0 && b.generation >= 0:
+			return a.generation < b.generation
+		case a.generation >= 0 || b.generation >= 0:
+			return a.generation >= 0
+		default:
+			return a.suffix > b.suffix
+		}
+	})
+	rotated := make([]string, 0, len(siblings))
+	for _, sibling := range siblings {
+		rotated = append(rotated, sibling.path)
+	}
+	return rotated
+}
+
+// readLines reads the lines of the file at path that match opts.Pattern.
+// Files ending in .gz or .bz2 are decompressed. The lines read before an
+// error are returned with it.
+func readLines(path string, fs utils.FileSystem, opts readOptions) ([]FileLine, error) {
 	if !utils.FileExists(fs, path) {
 		err := errors.New("file does not exist")
 		return nil, err
@@ -104,18 +468,222 @@ func readLines(path string, fs utils.FileSystem) ([]FileLine, error) {
 		}
 	}()
 
-	scanner := bufio.NewScanner(file)
-	scanner.Split(bufio.ScanLines)
+	// only uncompressed files can be read from the end, or from an offset
+	if opts.Last > 0 && !isCompressed(path) {
+		return readLastLines(file, path, opts)
+	}
+	if opts.From > 0 && !isCompressed(path) {
+		return readForwardAt(file, path, opts)
+	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		item := FileLine{Path: path, Line: line}
-		output = append(output, item)
+	reader, err := decompress(file, path)
+	if err != nil {
+		return nil, err
 	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			fmt.Printf("error closing decompressed reader: %s\n", err)
+		}
+	}()
 
-	if scanner.Err() != nil {
-		fmt.Printf("error: %s\n", scanner.Err())
+	return readForward(reader, path, opts)
+}
+
+func isCompressed(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz", ".bz2":
+		return true
+	}
+	return false
+}
+
+// decompress returns a reader of the content of r, decompressed if path ends
+// in .gz or .bz2.
+func decompress(r io.Reader, path string) (io.ReadCloser, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz":
+		return gzip.NewReader(r)
+	case ".bz2":
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	}
+	return io.NopCloser(r), nil
+}
+
+// readForwardAt reads the lines of file that start at or after opts.From,
+// without reading those before. Their line numbers are not known.
+func readForwardAt(file *os.File, path string, opts readOptions) ([]FileLine, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if opts.From >= info.Size() {
+		return nil, nil
 	}
 
+	// the line ending before opts.From, if the offset is within a line, is
+	// skipped so that reading starts at a line
+	start := opts.From - 1
+	reader := bufio.NewReader(io.NewSectionReader(file, start, info.Size()-start))
+	_, n, err := readLine(reader, 0)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	output, _, err := readForwardFrom(reader, path, opts, position{Offset: start + n})
+	for i := range output {
+		output[i].LineNumber = 0
+	}
+	return output, err
+}
+
+// position is a point in a file: the offset of a line and the number of
+// lines before it.
+type position struct {
+	Offset     int64
+	LineNumber int64
+}
+
+// readForward reads lines from the start of r, keeping only the last
+// opts.Last if set.
+func readForward(r io.Reader, path string, opts readOptions) ([]FileLine, error) {
+	output, _, err := readForwardFrom(r, path, opts, position{})
+	return output, err
+}
+
+// readForwardFrom reads lines from r, which starts at from in the file. It
+// also returns the position after the last line it read, and errMaxLines if
+// opts.MaxLines left lines out.
+func readForwardFrom(r io.Reader, path string, opts readOptions, from position) ([]FileLine, position, error) {
+	keepLast := opts.Last > 0 || opts.KeepLast
+	limit := opts.MaxLines
+	if opts.Last > 0 {
+		limit = min(opts.Last, opts.MaxLines)
+	}
+
+	var output []FileLine
+	matched := 0
+	reader := bufio.NewReader(r)
+	pos := from
+	for keepLast || len(output) < limit {
+		line, n, err := readLine(reader, opts.MaxLineLength)
+		if n > 0 && err == io.EOF && opts.CompleteLinesOnly {
+			// the line may still be being written
+			break
+		}
+		if n > 0 {
+			pos.LineNumber++
+			if pos.Offset >= opts.From && opts.match(line) {
+				matched++
+				output = append(output, FileLine{
+					Path:       path,
+					Line:       line,
+					LineNumber: pos.LineNumber,
+					ByteOffset: pos.Offset,
+				})
+			}
+			pos.Offset += n
+			if keepLast && len(output) >= 2*limit {
+				// drop the lines that can no longer be among the last
+				output = append(output[:0], output[len(output)-limit:]...)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return lastLines(output, limit), pos, err
+		}
+	}
+
+	wanted := matched
+	if opts.Last > 0 {
+		wanted = min(matched, opts.Last)
+	}
+	if keepLast && wanted > opts.MaxLines {
+		return lastLines(output, limit), pos, errMaxLines
+	}
+	if !keepLast && len(output) >= limit {
+		if _, err := reader.Peek(1); err == nil {
+			return output, pos, errMaxLines
+		}
+	}
+	return lastLines(output, limit), pos, nil
+}
+
+func lastLines(lines []FileLine, n int) []FileLine {
+	if len(lines) > n {
+		return lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// readLine reads a line, without its line ending, truncated to maxLength
+// bytes. It also returns the number of bytes the line took up in the file.
+func readLine(reader *bufio.Reader, maxLength int) (string, int64, error) {
+	var line []byte
+	var n int64
+	for {
+		fragment, err := reader.ReadSlice('\n')
+		n += int64(len(fragment))
+		if room := maxLength - len(line); room > 0 {
+			if len(fragment) > room {
+				line = append(line, fragment[:room]...)
+			} else {
+				line = append(line, fragment...)
+			}
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		// the line ending counts towards the length but is not part of the
+		// line
+		line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+		return string(line), n, err
+	}
+}
+
+// readLastLines reads the last opts.Last matching lines of file from its end, never
+// more than opts.MaxLines. The lines are returned in file order, with
+// errMaxLines if opts.MaxLines left lines out.
+func readLastLines(file *os.File, path string, opts readOptions) ([]FileLine, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	reader, err := utils.NewReverseLineReader(file, info.Size())
+	if err != nil {
+		return nil, err
+	}
+	reader.MaxLineLength = opts.MaxLineLength
+
+	count := min(opts.Last, opts.MaxLines)
+	var output []FileLine
+	for len(output) < count {
+		line, offset, err := reader.ReadLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if offset < opts.From {
+			break
+		}
+		if opts.match(line) {
+			output = append(output, FileLine{Path: path, Line: line, ByteOffset: offset})
+		}
+	}
+
+	for i, j := 0, len(output)-1; i < j; i, j = i+1, j-1 {
+		output[i], output[j] = output[j], output[i]
+	}
+	if len(output) == count && count < opts.Last {
+		if _, offset, err := reader.ReadLine(); err == nil && offset >= opts.From {
+			return output, errMaxLines
+		}
+	}
 	return output, nil
 }
@@ -1,11 +1,20 @@
 package fileline
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	_ "embed"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/macadmins/osquery-extension/pkg/utils"
+	"github.com/osquery/osquery-go/plugin/table"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -34,7 +43,7 @@ func TestProcessFile(t *testing.T) {
 
 		path := filepath.Join(filepath.Dir(tmpFile1.Name()), "testfile%-*.txt")
 		fs := utils.MockFileSystem{FileExists: true, Err: nil}
-		lines, err := processFile(path, true, fs)
+		lines, err := processFile(path, true, fs, defaultReadOptions())
 		assert.NoError(t, err)
 		assert.Len(t, lines, 4)
 	})
@@ -53,7 +62,7 @@ func TestProcessFile(t *testing.T) {
 
 		fs := utils.MockFileSystem{FileExists: true, Err: nil}
 
-		lines, err := processFile(tmpFile.Name(), false, fs)
+		lines, err := processFile(tmpFile.Name(), false, fs, defaultReadOptions())
 		assert.NoError(t, err)
 		assert.Len(t, lines, 2)
 	})
@@ -72,16 +81,450 @@ func TestReadLines(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NoError(t, tmpFile.Close())
 		fs := utils.MockFileSystem{FileExists: true, Err: nil}
-		lines, err := readLines(tmpFile.Name(), fs)
+		lines, err := readLines(tmpFile.Name(), fs, defaultReadOptions())
 		assert.NoError(t, err)
 		assert.Len(t, lines, 2)
 	})
 
 	t.Run("readLines file does not exist", func(t *testing.T) {
 		fs := utils.MockFileSystem{FileExists: false, Err: nil}
-		lines, err := readLines("nonexistentfile.txt", fs)
+		lines, err := readLines("nonexistentfile.txt", fs, defaultReadOptions())
 		assert.Error(t, err)
 		assert.Nil(t, lines)
 		assert.Equal(t, "file does not exist", err.Error())
 	})
 }
+
+// writeTestFile writes content to a file in a temporary directory.
+func writeTestFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.log")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestReadLinesOffsets(t *testing.T) {
+	path := writeTestFile(t, "first\r\nsecond\n\nfourth")
+	fs := utils.MockFileSystem{FileExists: true, Err: nil}
+
+	lines, err := readLines(path, fs, defaultReadOptions())
+	assert.NoError(t, err)
+	assert.Equal(t, []FileLine{
+		{Path: path, Line: "first", LineNumber: 1, ByteOffset: 0},
+		{Path: path, Line: "second", LineNumber: 2, ByteOffset: 7},
+		{Path: path, Line: "", LineNumber: 3, ByteOffset: 14},
+		{Path: path, Line: "fourth", LineNumber: 4, ByteOffset: 15},
+	}, lines)
+}
+
+func TestReadLinesLongLines(t *testing.T) {
+	// longer than both bufio.Scanner's limit and bufio.Reader's buffer
+	long := strings.Repeat("x", 100*1024)
+	path := writeTestFile(t, long+"\nafter\n")
+	fs := utils.MockFileSystem{FileExists: true, Err: nil}
+
+	lines, err := readLines(path, fs, defaultReadOptions())
+	assert.NoError(t, err)
+	assert.Len(t, lines, 2)
+	assert.Equal(t, long, lines[0].Line)
+	assert.Equal(t, FileLine{Path: path, Line: "after", LineNumber: 2, ByteOffset: int64(len(long)) + 1}, lines[1])
+
+	opts := defaultReadOptions()
+	opts.MaxLineLength = 10
+	lines, err = readLines(path, fs, opts)
+	assert.NoError(t, err)
+	assert.Len(t, lines, 2)
+	assert.Equal(t, strings.Repeat("x", 10), lines[0].Line)
+	assert.Equal(t, int64(len(long))+1, lines[1].ByteOffset)
+
+	opts.Last = 2
+	lines, err = readLines(path, fs, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []FileLine{
+		{Path: path, Line: strings.Repeat("x", 10), ByteOffset: 0},
+		{Path: path, Line: "after", ByteOffset: int64(len(long)) + 1},
+	}, lines)
+}
+
+func TestReadLinesLast(t *testing.T) {
+	path := writeTestFile(t, "line1\nline2\nline3\nline4\n")
+	fs := utils.MockFileSystem{FileExists: true, Err: nil}
+
+	opts := defaultReadOptions()
+	opts.Last = 2
+	lines, err := readLines(path, fs, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []FileLine{
+		{Path: path, Line: "line3", ByteOffset: 12},
+		{Path: path, Line: "line4", ByteOffset: 18},
+	}, lines)
+
+	// more than the file has
+	opts.Last = 10
+	lines, err = readLines(path, fs, opts)
+	assert.NoError(t, err)
+	assert.Len(t, lines, 4)
+	assert.Equal(t, "line1", lines[0].Line)
+
+	// max_lines caps last
+	opts.MaxLines = 1
+	lines, err = readLines(path, fs, opts)
+	assert.ErrorIs(t, err, errMaxLines)
+	assert.Equal(t, []FileLine{{Path: path, Line: "line4", ByteOffset: 18}}, lines)
+}
+
+func TestProcessFileMaxLines(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.log", "b.log", "c.log"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("line1\nline2\n"), 0644))
+	}
+	fs := utils.MockFileSystem{FileExists: true, Err: nil}
+
+	opts := defaultReadOptions()
+	opts.MaxLines = 3
+	lines, err := processFile(filepath.Join(dir, "%.log"), true, fs, opts)
+	assert.NoError(t, err)
+	assert.Len(t, lines, 4)
+	assert.Equal(t, filepath.Join(dir, "b.log"), lines[2].Path)
+	assert.Equal(t, int64(1), lines[2].LineNumber)
+	// a row says the result was cut short
+	assert.Equal(t, FileLine{Path: filepath.Join(dir, "b.log"), Error: errMaxLines.Error()}, lines[3])
+
+	// the files left unread are reported too
+	opts.MaxLines = 4
+	lines, err = processFile(filepath.Join(dir, "%.log"), true, fs, opts)
+	assert.NoError(t, err)
+	assert.Len(t, lines, 5)
+	assert.Equal(t, FileLine{Path: filepath.Join(dir, "c.log"), Error: errMaxLines.Error()}, lines[4])
+
+	// but not when every line fit
+	opts.MaxLines = 6
+	lines, err = processFile(filepath.Join(dir, "%.log"), true, fs, opts)
+	assert.NoError(t, err)
+	assert.Len(t, lines, 6)
+
+	// last applies to each file
+	opts = defaultReadOptions()
+	opts.Last = 1
+	lines, err = processFile(filepath.Join(dir, "%.log"), true, fs, opts)
+	assert.NoError(t, err)
+	assert.Len(t, lines, 3)
+	for _, line := range lines {
+		assert.Equal(t, "line2", line.Line)
+	}
+}
+
+func TestFileLineGenerate(t *testing.T) {
+	path := writeTestFile(t, "line1\nline2\nline3\n")
+	constraints := func(values map[string]string) table.QueryContext {
+		queryContext := table.QueryContext{Constraints: map[string]table.ConstraintList{}}
+		for column, value := range values {
+			queryContext.Constraints[column] = table.ConstraintList{
+				Constraints: []table.Constraint{{Operator: table.OperatorEquals, Expression: value}},
+			}
+		}
+		return queryContext
+	}
+
+	rows, err := FileLineGenerate(context.Background(), constraints(map[string]string{"path": path, "last": "1"}))
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{
+			"line":            "line3",
+			"path":            path,
+			"line_number":     "",
+			"byte_offset":     "12",
+			"last":            "1",
+			"max_lines":       "",
+			"max_line_length": "",
+			"pattern":         "",
+			"include_rotated": "",
+			"error":           "",
+			"checkpoint":      "",
+		},
+	}, rows)
+
+	rows, err = FileLineGenerate(context.Background(), constraints(map[string]string{"path": path, "max_lines": "2"}))
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, "2", rows[1]["line_number"])
+	assert.Equal(t, "2", rows[1]["max_lines"])
+	assert.Equal(t, errMaxLines.Error(), rows[2]["error"])
+	assert.Equal(t, "2", rows[2]["max_lines"])
+
+	_, err = FileLineGenerate(context.Background(), constraints(map[string]string{"path": path, "last": "-1"}))
+	assert.Error(t, err)
+}
+
+func TestReadLinesFrom(t *testing.T) {
+	content := "line1\nline2\nline3\n"
+	path := writeTestFile(t, content)
+	fs := utils.MockFileSystem{FileExists: true, Err: nil}
+
+	opts := defaultReadOptions()
+	opts.From = 6
+	lines, err := readLines(path, fs, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []FileLine{
+		{Path: path, Line: "line2", ByteOffset: 6},
+		{Path: path, Line: "line3", ByteOffset: 12},
+	}, lines)
+
+	// an offset within a line starts at the next one
+	opts.From = 7
+	lines, err = readLines(path, fs, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []FileLine{{Path: path, Line: "line3", ByteOffset: 12}}, lines)
+
+	opts.From = int64(len(content))
+	lines, err = readLines(path, fs, opts)
+	assert.NoError(t, err)
+	assert.Empty(t, lines)
+
+	// last does not go back past the offset
+	opts.From = 7
+	opts.Last = 2
+	lines, err = readLines(path, fs, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []FileLine{{Path: path, Line: "line3", ByteOffset: 12}}, lines)
+
+	// compressed files are read up to the offset, so line numbers are known
+	compressed := path + ".gz"
+	assert.NoError(t, os.WriteFile(compressed, []byte(gzipped(t, content)), 0644))
+	opts.Last = 0
+	lines, err = readLines(compressed, fs, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []FileLine{{Path: compressed, Line: "line3", LineNumber: 3, ByteOffset: 12}}, lines)
+}
+
+func TestFileLineGeneratePaging(t *testing.T) {
+	var content strings.Builder
+	for i := 1; i <= 25; i++ {
+		fmt.Fprintf(&content, "line%d\n", i)
+	}
+	path := writeTestFile(t, content.String())
+
+	var got []string
+	pages := 0
+	after := ""
+	for {
+		queryContext := table.QueryContext{Constraints: map[string]table.ConstraintList{
+			"path":      {Constraints: []table.Constraint{{Operator: table.OperatorEquals, Expression: path}}},
+			"max_lines": {Constraints: []table.Constraint{{Operator: table.OperatorEquals, Expression: "10"}}},
+		}}
+		if after != "" {
+			queryContext.Constraints["byte_offset"] = table.ConstraintList{
+				Constraints: []table.Constraint{{Operator: table.OperatorGreaterThan, Expression: after}},
+			}
+		}
+		rows, err := FileLineGenerate(context.Background(), queryContext)
+		assert.NoError(t, err)
+		pages++
+
+		after = ""
+		for _, row := range rows {
+			if row["error"] != "" {
+				assert.Equal(t, errMaxLines.Error(), row["error"])
+				// the row says where the next page starts
+				after = row["byte_offset"]
+				continue
+			}
+			got = append(got, row["line"])
+		}
+		if after == "" || pages > 5 {
+			break
+		}
+	}
+
+	assert.Equal(t, 3, pages)
+	assert.Len(t, got, 25)
+	assert.Equal(t, "line1", got[0])
+	assert.Equal(t, "line11", got[10])
+	assert.Equal(t, "line25", got[24])
+
+	queryContext := table.QueryContext{Constraints: map[string]table.ConstraintList{
+		"path":        {Constraints: []table.Constraint{{Operator: table.OperatorEquals, Expression: path}}},
+		"byte_offset": {Constraints: []table.Constraint{{Operator: table.OperatorGreaterThanOrEquals, Expression: "-1"}}},
+	}}
+	_, err := FileLineGenerate(context.Background(), queryContext)
+	assert.Error(t, err)
+}
+
+func TestReadLinesPattern(t *testing.T) {
+	path := writeTestFile(t, "INFO start\nERROR disk full\nINFO retry\nERROR disk full again\n")
+	fs := utils.MockFileSystem{FileExists: true, Err: nil}
+
+	opts := defaultReadOptions()
+	opts.Pattern = regexp.MustCompile(`^ERROR`)
+	lines, err := readLines(path, fs, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []FileLine{
+		{Path: path, Line: "ERROR disk full", LineNumber: 2, ByteOffset: 11},
+		{Path: path, Line: "ERROR disk full again", LineNumber: 4, ByteOffset: 38},
+	}, lines)
+
+	// the last matching lines, not the matches among the last lines
+	opts.Last = 1
+	lines, err = readLines(path, fs, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []FileLine{{Path: path, Line: "ERROR disk full again", ByteOffset: 38}}, lines)
+}
+
+func gzipped(t *testing.T, content string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(content))
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+	return buf.String()
+}
+
+// the standard library can decompress but not compress bzip2
+//
+//go:embed test_lines.bz2
+var testBzip2 string
+
+// writeRotatedFiles writes the files into dir, each modified an hour before
+// the one before it, as rotation leaves them.
+func writeRotatedFiles(t *testing.T, dir string, files [][2]string) {
+	t.Helper()
+	modTime := time.Now()
+	for _, file := range files {
+		path := filepath.Join(dir, file[0])
+		assert.NoError(t, os.WriteFile(path, []byte(file[1]), 0644))
+		assert.NoError(t, os.Chtimes(path, modTime, modTime))
+		modTime = modTime.Add(-time.Hour)
+	}
+}
+
+func TestProcessFileIncludeRotated(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writeRotatedFiles(t, dir, [][2]string{
+		{"app.log.lock", ""},
+		{"app.log", "current\n"},
+		{"app.log.1", "rotated\n"},
+		{"app.log.2.gz", gzipped(t, "gz line1\ngz line2\n")},
+		{"app.log.3.bz2", testBzip2},
+		{"other.log.1", "other\n"},
+	})
+	fs := utils.MockFileSystem{FileExists: true, Err: nil}
+
+	lines, err := processFile(path, false, fs, defaultReadOptions())
+	assert.NoError(t, err)
+	assert.Len(t, lines, 1)
+
+	opts := defaultReadOptions()
+	opts.IncludeRotated = true
+	lines, err = processFile(path, false, fs, opts)
+	assert.NoError(t, err)
+	var got []string
+	for _, line := range lines {
+		assert.Empty(t, line.Error)
+		got = append(got, filepath.Base(line.Path)+": "+line.Line)
+	}
+	assert.Equal(t, []string{
+		"app.log.3.bz2: bz2 line1",
+		"app.log.3.bz2: bz2 line2",
+		"app.log.2.gz: gz line1",
+		"app.log.2.gz: gz line2",
+		"app.log.1: rotated",
+		"app.log: current",
+	}, got)
+
+	// last reads compressed files from the start
+	opts.Last = 1
+	lines, err = processFile(path, false, fs, opts)
+	assert.NoError(t, err)
+	assert.Len(t, lines, 4)
+	assert.Equal(t, FileLine{Path: path + ".2.gz", Line: "gz line2", LineNumber: 2, ByteOffset: 9}, lines[1])
+
+	// max_lines keeps the newest lines
+	opts = defaultReadOptions()
+	opts.IncludeRotated = true
+	opts.MaxLines = 3
+	lines, err = processFile(path, false, fs, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []FileLine{
+		{Path: path + ".2.gz", Error: errMaxLines.Error()},
+		{Path: path + ".2.gz", Line: "gz line2", LineNumber: 2, ByteOffset: 9},
+		{Path: path + ".1", Line: "rotated", LineNumber: 1},
+		{Path: path, Line: "current", LineNumber: 1},
+	}, lines)
+}
+
+func TestRotatedFilesMixed(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	// logrotate switched to dated names after app.log.2.gz was rotated
+	writeRotatedFiles(t, dir, [][2]string{
+		{"app.log", ""},
+		{"app.log.20240102.gz", ""},
+		{"app.log.20240101", ""},
+		{"app.log.1", ""},
+		{"app.log.2.gz", ""},
+	})
+
+	var got []string
+	for _, rotated := range rotatedFiles(path) {
+		got = append(got, filepath.Base(rotated))
+	}
+	assert.Equal(t, []string{"app.log.20240102.gz", "app.log.20240101", "app.log.1", "app.log.2.gz"}, got)
+
+	// without modification times to go by, generations and dates are used
+	modTime := time.Now()
+	for _, name := range got {
+		assert.NoError(t, os.Chtimes(filepath.Join(dir, name), modTime, modTime))
+	}
+	got = nil
+	for _, rotated := range rotatedFiles(path) {
+		got = append(got, filepath.Base(rotated))
+	}
+	assert.Equal(t, []string{"app.log.1", "app.log.2.gz", "app.log.20240102.gz", "app.log.20240101"}, got)
+}
+
+func TestProcessFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.log"), []byte("line1\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "b.log.gz"), []byte("not gzip"), 0644))
+	fs := utils.MockFileSystem{FileExists: true, Err: nil}
+
+	lines, err := processFile(filepath.Join(dir, "%.log%"), true, fs, defaultReadOptions())
+	assert.NoError(t, err)
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "line1", lines[0].Line)
+	assert.Equal(t, filepath.Join(dir, "b.log.gz"), lines[1].Path)
+	assert.NotEmpty(t, lines[1].Error)
+
+	lines, err = processFile(filepath.Join(dir, "missing.log"), false, fs, defaultReadOptions())
+	assert.NoError(t, err)
+	assert.Equal(t, []FileLine{{Path: filepath.Join(dir, "missing.log"), Error: "file does not exist"}}, lines)
+
+	lines, err = processFile("", false, fs, defaultReadOptions())
+	assert.NoError(t, err)
+	assert.Empty(t, lines)
+}
+
+func TestFileLineGeneratePattern(t *testing.T) {
+	path := writeTestFile(t, "a1\nb2\na3\n")
+	queryContext := table.QueryContext{Constraints: map[string]table.ConstraintList{
+		"path":            {Constraints: []table.Constraint{{Operator: table.OperatorEquals, Expression: path}}},
+		"pattern":         {Constraints: []table.Constraint{{Operator: table.OperatorEquals, Expression: `^a\d$`}}},
+		"include_rotated": {Constraints: []table.Constraint{{Operator: table.OperatorEquals, Expression: "1"}}},
+	}}
+
+	rows, err := FileLineGenerate(context.Background(), queryContext)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, "a3", rows[1]["line"])
+	assert.Equal(t, "3", rows[1]["line_number"])
+	assert.Equal(t, `^a\d$`, rows[1]["pattern"])
+	assert.Equal(t, "1", rows[1]["include_rotated"])
+
+	queryContext.Constraints["pattern"] = table.ConstraintList{
+		Constraints: []table.Constraint{{Operator: table.OperatorEquals, Expression: "("}},
+	}
+	_, err = FileLineGenerate(context.Background(), queryContext)
+	assert.Error(t, err)
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package fileline
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileInode returns the inode of a file, by which a rotated file is told from
+// the one that replaced it.
+func fileInode(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
@@ -0,0 +1,93 @@
+package firefox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"runtime"
+	"sort"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/pkg/errors"
+)
+
+// policyPaths are the policies.json files Firefox reads enterprise policies
+// from: the system-wide directory on Linux and the distribution directory of
+// each install.
+var policyPaths = map[string][]string{
+	"darwin": {
+		"/Applications/Firefox.app/Contents/Resources/distribution/policies.json",
+	},
+	"linux": {
+		"/etc/firefox/policies/policies.json",
+		"/usr/lib/firefox/distribution/policies.json",
+		"/usr/lib64/firefox/distribution/policies.json",
+		"/usr/lib/firefox-esr/distribution/policies.json",
+		"/opt/firefox/distribution/policies.json",
+	},
+}
+
+type policiesFile struct {
+	Policies map[string]json.RawMessage `json:"policies"`
+}
+
+func FirefoxPoliciesColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("name"),
+		table.TextColumn("value"),
+		table.TextColumn("source"),
+	}
+}
+
+func FirefoxPoliciesGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	var results []map[string]string
+	for _, path := range policyPaths[runtime.GOOS] {
+		rows, err := policyRows(path)
+		if err != nil {
+			// Firefox ignores a policies.json it cannot parse, and so do we
+			log.Printf("error reading Firefox policies %s: %s", path, err)
+			continue
+		}
+		results = append(results, rows...)
+	}
+	return results, nil
+}
+
+// policyRows returns a row for each policy in a policies.json file, with the
+// policy value as compact JSON. A missing file has no policies.
+func policyRows(path string) ([]map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "read policies.json")
+	}
+
+	var file policiesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, errors.Wrap(err, "decode policies.json")
+	}
+
+	names := make([]string, 0, len(file.Policies))
+	for name := range file.Policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var results []map[string]string
+	for _, name := range names {
+		var value bytes.Buffer
+		if err := json.Compact(&value, file.Policies[name]); err != nil {
+			return nil, errors.Wrapf(err, "encode policy %s", name)
+		}
+		results = append(results, map[string]string{
+			"name":   name,
+			"value":  value.String(),
+			"source": path,
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,69 @@
+package firefox
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/macadmins/osquery-extension/pkg/users/userstest"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPoliciesJSON = `{
+  "policies": {
+    "DisableTelemetry": true,
+    "Homepage": {
+      "URL": "https://intranet.example.com",
+      "Locked": true
+    },
+    "ExtensionSettings": {
+      "uBlock0@raymondhill.net": {"installation_mode": "force_installed"}
+    }
+  }
+}`
+
+func TestPolicyRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.json")
+	require.NoError(t, os.WriteFile(path, []byte(testPoliciesJSON), 0644))
+
+	rows, err := policyRows(path)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{"name": "DisableTelemetry", "value": "true", "source": path},
+		{"name": "ExtensionSettings", "value": `{"uBlock0@raymondhill.net":{"installation_mode":"force_installed"}}`, "source": path},
+		{"name": "Homepage", "value": `{"URL":"https://intranet.example.com","Locked":true}`, "source": path},
+	}, rows)
+}
+
+func TestPolicyRowsMissing(t *testing.T) {
+	rows, err := policyRows(filepath.Join(t.TempDir(), "policies.json"))
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+}
+
+func TestFirefoxPoliciesGenerate(t *testing.T) {
+	dir := t.TempDir()
+	system := filepath.Join(dir, "etc", "policies.json")
+	distribution := filepath.Join(dir, "distribution", "policies.json")
+	broken := filepath.Join(dir, "broken", "policies.json")
+	userstest.WriteMockFiles(t, dir, map[string]string{
+		"etc/policies.json":          `{"policies": {"DisableTelemetry": true}}`,
+		"distribution/policies.json": `{"policies": {"DisableAppUpdate": true}}`,
+		"broken/policies.json":       `{"policies": `,
+	})
+
+	original := policyPaths[runtime.GOOS]
+	policyPaths[runtime.GOOS] = []string{system, broken, filepath.Join(dir, "missing.json"), distribution}
+	t.Cleanup(func() { policyPaths[runtime.GOOS] = original })
+
+	rows, err := FirefoxPoliciesGenerate(context.Background(), table.QueryContext{})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{"name": "DisableTelemetry", "value": "true", "source": system},
+		{"name": "DisableAppUpdate", "value": "true", "source": distribution},
+	}, rows)
+}
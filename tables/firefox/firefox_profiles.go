@@ -0,0 +1,139 @@
+package firefox
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/macadmins/osquery-extension/pkg/users"
+	"github.com/macadmins/osquery-extension/pkg/utils"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/pkg/errors"
+)
+
+// userLister lists the users whose homes are searched for Firefox profiles.
+var userLister users.Lister = users.OSLister{}
+
+// profileDirs are where Firefox keeps profiles.ini, relative to each home.
+var profileDirs = map[string][]string{
+	"darwin": {"Library/Application Support/Firefox"},
+	"linux":  {".mozilla/firefox", "snap/firefox/common/.mozilla/firefox"},
+}
+
+// firefoxInstall is an installation of Firefox, identified by the hash of its
+// install directory, and the profile it starts with.
+type firefoxInstall struct {
+	Hash    string
+	Default string
+	Locked  bool
+}
+
+func FirefoxProfilesColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("username"),
+		table.TextColumn("name"),
+		table.TextColumn("path"),
+		table.IntegerColumn("default"),
+		table.TextColumn("install"),
+		table.IntegerColumn("locked"),
+		table.TextColumn("profiles_ini"),
+	}
+}
+
+func FirefoxProfilesGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	accounts, err := userLister.List(users.WithUsernames(queryContext))
+	if err != nil {
+		return nil, errors.Wrap(err, "list users")
+	}
+
+	var results []map[string]string
+	for _, account := range accounts {
+		for _, dir := range profileDirs[runtime.GOOS] {
+			rows, err := profileRows(filepath.Join(account.Home, dir))
+			if err != nil {
+				log.Printf("error reading Firefox profiles of %s: %s", account.Username, err)
+				continue
+			}
+			for _, row := range rows {
+				row["username"] = account.Username
+			}
+			results = append(results, rows...)
+		}
+	}
+
+	return results, nil
+}
+
+// profileRows returns a row for each profile listed in the profiles.ini of
+// dir. Each names the installs that start with the profile, and whether one of
+// them is locked to it.
+func profileRows(dir string) ([]map[string]string, error) {
+	profilesPath := filepath.Join(dir, "profiles.ini")
+	profiles, err := loadIni(profilesPath)
+	if err != nil {
+		return nil, err
+	}
+	installSections, err := loadIni(filepath.Join(dir, "installs.ini"))
+	if err != nil {
+		return nil, err
+	}
+
+	installs := map[string]firefoxInstall{}
+	for _, section := range installSections {
+		installs[section.Name] = installFromSection(section.Name, section)
+	}
+	// profiles.ini repeats installs.ini in Install sections, and is the only
+	// place newer versions of Firefox keep them
+	for _, section := range profiles {
+		if hash, ok := strings.CutPrefix(section.Name, "Install"); ok {
+			installs[hash] = installFromSection(hash, section)
+		}
+	}
+
+	var results []map[string]string
+	for _, section := range profiles {
+		if !strings.HasPrefix(section.Name, "Profile") {
+			continue
+		}
+		profilePath := section.Values["Path"]
+
+		var hashes []string
+		locked := false
+		for _, install := range installs {
+			if install.Default != profilePath {
+				continue
+			}
+			hashes = append(hashes, install.Hash)
+			locked = locked || install.Locked
+		}
+		sort.Strings(hashes)
+
+		path := profilePath
+		if section.Values["IsRelative"] == "1" {
+			path = filepath.Join(dir, filepath.FromSlash(profilePath))
+		}
+
+		results = append(results, map[string]string{
+			"name":         section.Values["Name"],
+			"path":         path,
+			"default":      strconv.Itoa(utils.BoolToInt(section.Values["Default"] == "1")),
+			"install":      strings.Join(hashes, ","),
+			"locked":       strconv.Itoa(utils.BoolToInt(locked)),
+			"profiles_ini": profilesPath,
+		})
+	}
+
+	return results, nil
+}
+
+func installFromSection(hash string, section iniSection) firefoxInstall {
+	return firefoxInstall{
+		Hash:    hash,
+		Default: section.Values["Default"],
+		Locked:  section.Values["Locked"] == "1",
+	}
+}
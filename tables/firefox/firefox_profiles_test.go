@@ -0,0 +1,125 @@
+package firefox
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/macadmins/osquery-extension/pkg/users/userstest"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testProfilesIni = `[Install4F96D1932A9F858E]
+Default=Profiles/abcd1234.default-release
+Locked=1
+
+[Profile1]
+Name=default
+IsRelative=1
+Path=Profiles/wxyz9876.default
+Default=1
+
+[Profile0]
+Name=default-release
+IsRelative=1
+Path=Profiles/abcd1234.default-release
+
+[Profile2]
+Name=work
+IsRelative=0
+Path=/srv/firefox/work
+
+[General]
+StartWithLastProfile=1
+Version=2
+`
+
+const testInstallsIni = `[4F96D1932A9F858E]
+Default=Profiles/abcd1234.default-release
+Locked=1
+
+[2656FF1E876E9973]
+Default=Profiles/abcd1234.default-release
+`
+
+// setupFirefoxHome writes the given files into the Firefox directory of a
+// temporary home of testuser, and returns that directory.
+func setupFirefoxHome(t *testing.T, files map[string]string) string {
+	t.Helper()
+	user := userstest.MockHome(t, "testuser", nil)
+	dir := filepath.Join(user.Home, profileDirs[runtime.GOOS][0])
+	userstest.WriteMockFiles(t, dir, files)
+	userstest.SetMockUsers(t, &userLister, user, userstest.MockHome(t, "otheruser", nil))
+
+	return dir
+}
+
+func TestProfileRows(t *testing.T) {
+	dir := setupFirefoxHome(t, map[string]string{
+		"profiles.ini": testProfilesIni,
+		"installs.ini": testInstallsIni,
+	})
+	profilesIni := filepath.Join(dir, "profiles.ini")
+
+	rows, err := profileRows(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{
+			"name":         "default",
+			"path":         filepath.Join(dir, "Profiles", "wxyz9876.default"),
+			"default":      "1",
+			"install":      "",
+			"locked":       "0",
+			"profiles_ini": profilesIni,
+		},
+		{
+			"name":         "default-release",
+			"path":         filepath.Join(dir, "Profiles", "abcd1234.default-release"),
+			"default":      "0",
+			"install":      "2656FF1E876E9973,4F96D1932A9F858E",
+			"locked":       "1",
+			"profiles_ini": profilesIni,
+		},
+		{
+			"name":         "work",
+			"path":         "/srv/firefox/work",
+			"default":      "0",
+			"install":      "",
+			"locked":       "0",
+			"profiles_ini": profilesIni,
+		},
+	}, rows)
+}
+
+func TestProfileRowsNoFirefox(t *testing.T) {
+	rows, err := profileRows(t.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+}
+
+func TestFirefoxProfilesGenerate(t *testing.T) {
+	if _, ok := profileDirs[runtime.GOOS]; !ok {
+		t.Skip("Firefox profiles are not read on " + runtime.GOOS)
+	}
+	setupFirefoxHome(t, map[string]string{"profiles.ini": testProfilesIni})
+
+	rows, err := FirefoxProfilesGenerate(context.Background(), table.QueryContext{})
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	for _, row := range rows {
+		assert.Equal(t, "testuser", row["username"])
+	}
+	// profiles.ini alone carries the install
+	assert.Equal(t, "4F96D1932A9F858E", rows[1]["install"])
+
+	rows, err = FirefoxProfilesGenerate(context.Background(), table.QueryContext{
+		Constraints: map[string]table.ConstraintList{
+			"username": {Constraints: []table.Constraint{{Operator: table.OperatorEquals, Expression: "otheruser"}}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+}
@@ -0,0 +1,59 @@
+package firefox
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// iniSection is a section of the ini files Firefox keeps its profile list in.
+type iniSection struct {
+	Name   string
+	Values map[string]string
+}
+
+// loadIni parses an ini file into its sections, in file order. A missing file
+// has no sections.
+func loadIni(path string) ([]iniSection, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "open ini file")
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("close ini file: %v", err)
+		}
+	}()
+
+	var sections []iniSection
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			sections = append(sections, iniSection{
+				Name:   strings.TrimSpace(line[1 : len(line)-1]),
+				Values: map[string]string{},
+			})
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found || len(sections) == 0 {
+			continue
+		}
+		sections[len(sections)-1].Values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read ini file")
+	}
+
+	return sections, nil
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -57,16 +58,55 @@ func (md MunkiDate) String() string {
 	return t.UTC().Format("2006-01-02 15:04:05 +0000")
 }
 
+// munkiNumber can unmarshal both integer and real plist values, which Munki
+// uses interchangeably for sizes and durations. Valid is false when the key was
+// absent.
+type munkiNumber struct {
+	Value float64
+	Valid bool
+}
+
+// UnmarshalPlist handles plist unmarshaling for both integer and real types
+func (n *munkiNumber) UnmarshalPlist(unmarshal func(interface{}) error) error {
+	var f float64
+	if err := unmarshal(&f); err == nil {
+		*n = munkiNumber{Value: f, Valid: true}
+		return nil
+	}
+
+	var i int64
+	if err := unmarshal(&i); err != nil {
+		return err
+	}
+
+	*n = munkiNumber{Value: float64(i), Valid: true}
+	return nil
+}
+
+// String returns the number without trailing zeros, or an empty string when unset
+func (n munkiNumber) String() string {
+	if !n.Valid {
+		return ""
+	}
+	return strconv.FormatFloat(n.Value, 'f', -1, 64)
+}
+
 type munkiReport struct {
 	ConsoleUser           string
 	StartTime             MunkiDate
 	EndTime               MunkiDate
 	Errors                []string
 	Warnings              []string
-	ProblemInstalls       []string
+	ProblemInstalls       []pendingItem
 	ManagedInstallVersion string
 	ManifestName          string
+	RunType               string
 	ManagedInstalls       []managedInstall
+	ItemsToInstall        []pendingItem
+	ItemsToRemove         []pendingItem
+	AppleUpdates          []pendingItem
+	InstallResults        []installResult
+	RemovalResults        []installResult
 }
 
 type managedInstall struct {
@@ -104,7 +144,11 @@ func MunkiInfoGenerate(ctx context.Context, queryContext table.QueryContext) ([]
 
 	errors := strings.Join(report.Errors, ";")
 	warnings := strings.Join(report.Warnings, ";")
-	problemInstalls := strings.Join(report.ProblemInstalls, ";")
+	problemInstallNames := make([]string, 0, len(report.ProblemInstalls))
+	for _, item := range report.ProblemInstalls {
+		problemInstallNames = append(problemInstallNames, item.Name)
+	}
+	problemInstalls := strings.Join(problemInstallNames, ";")
 
 	results := []map[string]string{
 		{
@@ -160,15 +204,16 @@ func MunkiInstallsGenerate(ctx context.Context, queryContext table.QueryContext)
 
 }
 
-// reportPath is defined as a global variable to ease testing.
-var reportPath = "/Library/Managed Installs/ManagedInstallReport.plist"
-
 func loadMunkiReport(fs utils.FileSystem) (*munkiReport, error) {
+	return loadMunkiReportFile(fs, reportFilePath(fs))
+}
+
+func loadMunkiReportFile(fs utils.FileSystem, path string) (*munkiReport, error) {
 	var report munkiReport
-	if !utils.FileExists(fs, reportPath) {
+	if !utils.FileExists(fs, path) {
 		return nil, nil
 	}
-	file, err := os.Open(reportPath)
+	file, err := os.Open(path)
 	if err != nil {
 		return &report, errors.Wrap(err, "open ManagedInstallReport file")
 	}
@@ -0,0 +1,145 @@
+package munki
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/macadmins/osquery-extension/pkg/utils"
+	"github.com/micromdm/plist"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/pkg/errors"
+)
+
+// pkgInfo is an item of a Munki catalog.
+type pkgInfo struct {
+	Name                   string                   `plist:"name"`
+	DisplayName            string                   `plist:"display_name"`
+	Version                string                   `plist:"version"`
+	Catalogs               []string                 `plist:"catalogs"`
+	MinimumOSVersion       string                   `plist:"minimum_os_version"`
+	MaximumOSVersion       string                   `plist:"maximum_os_version"`
+	SupportedArchitectures []string                 `plist:"supported_architectures"`
+	BlockingApplications   []string                 `plist:"blocking_applications"`
+	Requires               []string                 `plist:"requires"`
+	UpdateFor              []string                 `plist:"update_for"`
+	InstallerType          string                   `plist:"installer_type"`
+	InstallerItemSize      munkiNumber              `plist:"installer_item_size"`
+	Uninstallable          bool                     `plist:"uninstallable"`
+	UnattendedInstall      bool                     `plist:"unattended_install"`
+	Installs               []map[string]interface{} `plist:"installs"`
+}
+
+func MunkiCatalogItemsColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("catalog"),
+		table.TextColumn("name"),
+		table.TextColumn("display_name"),
+		table.TextColumn("version"),
+		table.TextColumn("catalogs"),
+		table.TextColumn("minimum_os_version"),
+		table.TextColumn("maximum_os_version"),
+		table.TextColumn("supported_architectures"),
+		table.TextColumn("blocking_applications"),
+		table.TextColumn("requires"),
+		table.TextColumn("update_for"),
+		table.TextColumn("installer_type"),
+		table.BigIntColumn("installer_item_size"),
+		table.TextColumn("uninstallable"),
+		table.TextColumn("unattended_install"),
+		table.TextColumn("installs"),
+	}
+}
+
+func MunkiCatalogItemsGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	fs := utils.OSFileSystem{}
+	catalogDir := filepath.Join(managedInstallDir(fs), "catalogs")
+
+	names := map[string]bool{}
+	if constraintList, present := queryContext.Constraints["name"]; present {
+		// 'name' is in the where clause
+		for _, constraint := range constraintList.Constraints {
+			// =
+			if constraint.Operator == table.OperatorEquals {
+				names[constraint.Expression] = true
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(catalogDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "read Munki catalogs directory")
+	}
+
+	var results []map[string]string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		items, err := loadMunkiCatalog(filepath.Join(catalogDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if len(names) > 0 && !names[item.Name] {
+				continue
+			}
+			row, err := catalogItemRow(entry.Name(), item)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, row)
+		}
+	}
+
+	return results, nil
+}
+
+func catalogItemRow(catalog string, item pkgInfo) (map[string]string, error) {
+	installs := ""
+	if len(item.Installs) > 0 {
+		jsonStr, err := json.Marshal(item.Installs)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshal installs")
+		}
+		installs = string(jsonStr)
+	}
+
+	return map[string]string{
+		"catalog":                 catalog,
+		"name":                    item.Name,
+		"display_name":            item.DisplayName,
+		"version":                 item.Version,
+		"catalogs":                strings.Join(item.Catalogs, ";"),
+		"minimum_os_version":      item.MinimumOSVersion,
+		"maximum_os_version":      item.MaximumOSVersion,
+		"supported_architectures": strings.Join(item.SupportedArchitectures, ";"),
+		"blocking_applications":   strings.Join(item.BlockingApplications, ";"),
+		"requires":                strings.Join(item.Requires, ";"),
+		"update_for":              strings.Join(item.UpdateFor, ";"),
+		"installer_type":          item.InstallerType,
+		"installer_item_size":     item.InstallerItemSize.String(),
+		"uninstallable":           fmt.Sprintf("%v", item.Uninstallable),
+		"unattended_install":      fmt.Sprintf("%v", item.UnattendedInstall),
+		"installs":                installs,
+	}, nil
+}
+
+func loadMunkiCatalog(path string) ([]pkgInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read Munki catalog")
+	}
+
+	var items []pkgInfo
+	if err := plist.Unmarshal(data, &items); err != nil {
+		return nil, errors.Wrapf(err, "decode Munki catalog %s", path)
+	}
+	return items, nil
+}
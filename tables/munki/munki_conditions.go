@@ -0,0 +1,97 @@
+package munki
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/macadmins/osquery-extension/pkg/utils"
+	"github.com/micromdm/plist"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/pkg/errors"
+)
+
+func MunkiConditionsColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("key"),
+		table.TextColumn("value"),
+		table.TextColumn("type"),
+	}
+}
+
+func MunkiConditionsGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	fs := utils.OSFileSystem{}
+	conditions, err := loadMunkiConditions(fs, filepath.Join(managedInstallDir(fs), "ConditionalItems.plist"))
+	if err != nil {
+		return nil, err
+	}
+
+	return buildConditionsOutput(conditions)
+}
+
+func buildConditionsOutput(conditions map[string]interface{}) ([]map[string]string, error) {
+	keys := make([]string, 0, len(conditions))
+	for key := range conditions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var results []map[string]string
+	for _, key := range keys {
+		value, err := plistValueString(conditions[key])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, map[string]string{
+			"key":   key,
+			"value": value,
+			"type":  plistValueType(conditions[key]),
+		})
+	}
+
+	return results, nil
+}
+
+// loadMunkiConditions reads the condition values written by Munki before
+// evaluating conditional_items, including those of admin-provided conditions.
+func loadMunkiConditions(fs utils.FileSystem, path string) (map[string]interface{}, error) {
+	if !utils.FileExists(fs, path) {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read ConditionalItems")
+	}
+
+	var conditions map[string]interface{}
+	if err := plist.Unmarshal(data, &conditions); err != nil {
+		return nil, errors.Wrapf(err, "decode ConditionalItems %s", path)
+	}
+	return conditions, nil
+}
+
+// plistValueType returns the plist type name of a decoded value.
+func plistValueType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int64, uint64:
+		return "integer"
+	case float32, float64:
+		return "real"
+	case time.Time:
+		return "date"
+	case []byte:
+		return "data"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "dictionary"
+	default:
+		return "unknown"
+	}
+}
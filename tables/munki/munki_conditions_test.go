@@ -0,0 +1,44 @@
+package munki
+
+import (
+	"context"
+	_ "embed"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed test_ConditionalItems.plist
+var testConditionalItems []byte
+
+func TestMunkiConditionsGenerate(t *testing.T) {
+	reportPath := testReportPath(t)
+	conditionsPath := filepath.Join(filepath.Dir(reportPath), "ConditionalItems.plist")
+	require.NoError(t, os.WriteFile(conditionsPath, testConditionalItems, 0600))
+
+	rows, err := MunkiConditionsGenerate(context.Background(), table.QueryContext{})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{"key": "arch", "value": "arm64", "type": "string"},
+		{"key": "catalogs", "value": `["production"]`, "type": "array"},
+		{"key": "date", "value": "2024-01-14 12:00:00 +0000", "type": "date"},
+		{"key": "has_battery", "value": "true", "type": "boolean"},
+		{"key": "machine_model", "value": "MacBookPro18,3", "type": "string"},
+		{"key": "os_vers_major", "value": "14", "type": "integer"},
+		{"key": "os_version", "value": "14.2.1", "type": "string"},
+		{"key": "physical_or_virtual", "value": "physical", "type": "string"},
+		{"key": "site_uptime_days", "value": "3.5", "type": "real"},
+	}, rows)
+}
+
+func TestMunkiConditionsGenerateMissing(t *testing.T) {
+	testReportPath(t)
+
+	rows, err := MunkiConditionsGenerate(context.Background(), table.QueryContext{})
+	assert.NoError(t, err)
+	assert.Empty(t, rows)
+}
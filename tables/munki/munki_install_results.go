@@ -0,0 +1,82 @@
+package munki
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/macadmins/osquery-extension/pkg/utils"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+// installResult is an entry of InstallResults or RemovalResults. Munki records
+// the exit status of the installer, so a status of 0 is a success.
+type installResult struct {
+	Name                 string      `plist:"name"`
+	DisplayName          string      `plist:"display_name"`
+	Version              string      `plist:"version"`
+	Status               int         `plist:"status"`
+	Time                 MunkiDate   `plist:"time"`
+	AppleUpdate          bool        `plist:"applesus"`
+	Unattended           bool        `plist:"unattended"`
+	DurationSeconds      munkiNumber `plist:"duration_seconds"`
+	DownloadKBytesPerSec munkiNumber `plist:"download_kbytes_per_sec"`
+	InstallerItemSize    munkiNumber `plist:"installer_item_size"`
+}
+
+func MunkiInstallResultsColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("type"),
+		table.TextColumn("name"),
+		table.TextColumn("display_name"),
+		table.TextColumn("version"),
+		table.IntegerColumn("status"),
+		table.TextColumn("success"),
+		table.TextColumn("time"),
+		table.DoubleColumn("duration_seconds"),
+		table.DoubleColumn("download_kbytes_per_sec"),
+		table.BigIntColumn("installer_item_size"),
+		table.TextColumn("apple_update"),
+		table.TextColumn("unattended"),
+		table.TextColumn("end_time"),
+	}
+}
+
+func MunkiInstallResultsGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	fs := utils.OSFileSystem{}
+	report, err := loadMunkiReport(fs)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil {
+		return nil, nil
+	}
+
+	var results []map[string]string
+	for _, result := range report.InstallResults {
+		results = append(results, installResultRow("install", result, report.EndTime))
+	}
+	for _, result := range report.RemovalResults {
+		results = append(results, installResultRow("removal", result, report.EndTime))
+	}
+
+	return results, nil
+}
+
+func installResultRow(resultType string, result installResult, endTime MunkiDate) map[string]string {
+	return map[string]string{
+		"type":                    resultType,
+		"name":                    result.Name,
+		"display_name":            result.DisplayName,
+		"version":                 result.Version,
+		"status":                  strconv.Itoa(result.Status),
+		"success":                 fmt.Sprintf("%v", result.Status == 0),
+		"time":                    result.Time.String(),
+		"duration_seconds":        result.DurationSeconds.String(),
+		"download_kbytes_per_sec": result.DownloadKBytesPerSec.String(),
+		"installer_item_size":     result.InstallerItemSize.String(),
+		"apple_update":            fmt.Sprintf("%v", result.AppleUpdate),
+		"unattended":              fmt.Sprintf("%v", result.Unattended),
+		"end_time":                endTime.String(),
+	}
+}
@@ -0,0 +1,101 @@
+package munki
+
+import (
+	"context"
+	_ "embed"
+	"os"
+	"testing"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/assert"
+)
+
+//go:embed test_ManagedInstallReport_with_results.plist
+var testManagedInstallReportWithResults []byte
+
+func TestMunkiInstallResultsGenerate(t *testing.T) {
+	reportPath := testReportPath(t)
+	err := os.WriteFile(reportPath, testManagedInstallReportWithResults, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := MunkiInstallResultsGenerate(context.Background(), table.QueryContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedRows := []map[string]string{
+		{
+			"type":                    "install",
+			"name":                    "1Password",
+			"display_name":            "1Password",
+			"version":                 "8.10.44",
+			"status":                  "0",
+			"success":                 "true",
+			"time":                    "2024-01-14 11:59:00 +0000",
+			"duration_seconds":        "12.5",
+			"download_kbytes_per_sec": "20480",
+			"installer_item_size":     "125000",
+			"apple_update":            "false",
+			"unattended":              "true",
+			"end_time":                "2024-01-14 12:00:00 +0000",
+		},
+		{
+			"type":                    "install",
+			"name":                    "Slack",
+			"display_name":            "Slack",
+			"version":                 "4.47.72",
+			"status":                  "-1",
+			"success":                 "false",
+			"time":                    "2024-01-14 11:59:30 +0000",
+			"duration_seconds":        "",
+			"download_kbytes_per_sec": "",
+			"installer_item_size":     "",
+			"apple_update":            "false",
+			"unattended":              "false",
+			"end_time":                "2024-01-14 12:00:00 +0000",
+		},
+		{
+			"type":                    "install",
+			"name":                    "Safari17.2.1VenturaAuto",
+			"display_name":            "Safari",
+			"version":                 "17.2.1",
+			"status":                  "0",
+			"success":                 "true",
+			"time":                    "2024-01-14 11:59:45 +0000",
+			"duration_seconds":        "",
+			"download_kbytes_per_sec": "",
+			"installer_item_size":     "",
+			"apple_update":            "true",
+			"unattended":              "false",
+			"end_time":                "2024-01-14 12:00:00 +0000",
+		},
+		{
+			"type":                    "removal",
+			"name":                    "Zoom",
+			"display_name":            "Zoom",
+			"version":                 "",
+			"status":                  "0",
+			"success":                 "true",
+			"time":                    "2024-01-14 11:58:30 +0000",
+			"duration_seconds":        "",
+			"download_kbytes_per_sec": "",
+			"installer_item_size":     "",
+			"apple_update":            "false",
+			"unattended":              "true",
+			"end_time":                "2024-01-14 12:00:00 +0000",
+		},
+	}
+
+	assert.Equal(t, expectedRows, rows, "Install result rows are not equal")
+}
+
+func TestMunkiInstallResultsGenerateNoResults(t *testing.T) {
+	reportPath := testReportPath(t)
+	err := os.WriteFile(reportPath, testManagedInstallReportMunki7, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := MunkiInstallResultsGenerate(context.Background(), table.QueryContext{})
+	assert.NoError(t, err)
+	assert.Empty(t, rows)
+}
@@ -0,0 +1,312 @@
+package munki
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/macadmins/osquery-extension/pkg/utils"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/pkg/errors"
+)
+
+const (
+	logTimestampLayout = "Jan 2 2006 15:04:05 -0700"
+	runStartMarker     = "### Starting managedsoftwareupdate run"
+	runEndMarker       = "### Ending managedsoftwareupdate run"
+)
+
+var logLineRegex = regexp.MustCompile(`^([A-Z][a-z]{2} \d{1,2} \d{4} \d{2}:\d{2}:\d{2} [+-]\d{4}) (.*)$`)
+
+type munkiLogEntry struct {
+	Time    time.Time
+	Level   string
+	Message string
+	RunID   int64
+	Path    string
+}
+
+// logFilter holds the constraints used to decide how far back to read.
+type logFilter struct {
+	since     time.Time
+	sinceExpr string
+	runIDs    map[int64]bool
+	minRunID  int64
+	levels    map[string]bool
+}
+
+func MunkiLogsColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.BigIntColumn("timestamp"),
+		table.TextColumn("level"),
+		table.TextColumn("message"),
+		table.BigIntColumn("run_id"),
+		table.TextColumn("path"),
+		table.TextColumn("since"),
+	}
+}
+
+func MunkiLogsGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	fs := utils.OSFileSystem{}
+	filter, err := logFilterFromContext(queryContext)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := logFilePaths(munkiPreferenceString(fs, "LogFile"))
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := readMunkiLogs(paths, filter.stop)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []map[string]string
+	for _, entry := range entries {
+		if !filter.match(entry) {
+			continue
+		}
+		runID := ""
+		if entry.RunID != 0 {
+			runID = strconv.FormatInt(entry.RunID, 10)
+		}
+		results = append(results, map[string]string{
+			"timestamp": strconv.FormatInt(entry.Time.Unix(), 10),
+			"level":     entry.Level,
+			"message":   entry.Message,
+			"run_id":    runID,
+			"path":      entry.Path,
+			"since":     filter.sinceExpr,
+		})
+	}
+
+	return results, nil
+}
+
+func logFilterFromContext(queryContext table.QueryContext) (logFilter, error) {
+	filter := logFilter{runIDs: map[int64]bool{}, levels: map[string]bool{}}
+
+	if constraintList, present := queryContext.Constraints["since"]; present {
+		for _, constraint := range constraintList.Constraints {
+			if constraint.Operator != table.OperatorEquals {
+				continue
+			}
+			since, err := utils.ParseConstraintTime(constraint.Expression)
+			if err != nil {
+				return filter, err
+			}
+			filter.since = since
+			filter.sinceExpr = constraint.Expression
+		}
+	}
+
+	if constraintList, present := queryContext.Constraints["run_id"]; present {
+		for _, constraint := range constraintList.Constraints {
+			switch constraint.Operator {
+			case table.OperatorEquals, table.OperatorGreaterThan, table.OperatorGreaterThanOrEquals:
+			default:
+				continue
+			}
+			runID, err := strconv.ParseInt(constraint.Expression, 10, 64)
+			if err != nil {
+				return filter, errors.Wrapf(err, "parse run_id constraint %s", constraint.Expression)
+			}
+			if constraint.Operator == table.OperatorEquals {
+				filter.runIDs[runID] = true
+			}
+			if runID > filter.minRunID {
+				filter.minRunID = runID
+			}
+		}
+	}
+
+	if constraintList, present := queryContext.Constraints["level"]; present {
+		for _, constraint := range constraintList.Constraints {
+			if constraint.Operator == table.OperatorEquals {
+				filter.levels[constraint.Expression] = true
+			}
+		}
+	}
+
+	return filter, nil
+}
+
+// stop reports whether the run started at start, and so every run before it,
+// is older than anything the query can match.
+func (f logFilter) stop(start time.Time) bool {
+	if !f.since.IsZero() && start.Before(f.since) {
+		return true
+	}
+	return f.minRunID != 0 && start.Unix() <= f.minRunID
+}
+
+func (f logFilter) match(entry munkiLogEntry) bool {
+	if !f.since.IsZero() && entry.Time.Before(f.since) {
+		return false
+	}
+	if f.minRunID != 0 && entry.RunID < f.minRunID {
+		return false
+	}
+	if len(f.runIDs) > 0 && !f.runIDs[entry.RunID] {
+		return false
+	}
+	if len(f.levels) > 0 && !f.levels[entry.Level] {
+		return false
+	}
+	return true
+}
+
+// logFilePaths returns the log and the siblings Munki rotated it to, newest
+// first.
+func logFilePaths(logFile string) ([]string, error) {
+	matches, err := filepath.Glob(logFile + ".*")
+	if err != nil {
+		return nil, errors.Wrap(err, "list rotated Munki logs")
+	}
+
+	rotated := map[string]int{}
+	var paths []string
+	for _, match := range matches {
+		n, err := strconv.Atoi(strings.TrimPrefix(match, logFile+"."))
+		if err != nil {
+			continue
+		}
+		rotated[match] = n
+		paths = append(paths, match)
+	}
+	sort.Slice(paths, func(i, j int) bool { return rotated[paths[i]] < rotated[paths[j]] })
+
+	return append([]string{logFile}, paths...), nil
+}
+
+// readMunkiLogs reads the logs from the end, assigning each entry the run_id
+// of the run it was logged in: the unix time of the preceding start marker.
+// The log only has second precision, so runs started within the same second
+// share a run_id. managedsoftwareupdate does not run concurrently, so this
+// needs a run that ended within a second of starting.
+// Reading stops after the first start marker for which stop returns true.
+// Entries are returned oldest first.
+func readMunkiLogs(paths []string, stop func(time.Time) bool) ([]munkiLogEntry, error) {
+	var results, pending []munkiLogEntry
+	var continuation []string
+
+	for _, path := range paths {
+		done, err := readMunkiLogFile(path, func(entry munkiLogEntry, lines []string) bool {
+			if len(lines) > 0 {
+				// continuation lines were read last to first
+				for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+					lines[i], lines[j] = lines[j], lines[i]
+				}
+				entry.Message += "\n" + strings.Join(lines, "\n")
+			}
+
+			switch {
+			case strings.HasPrefix(entry.Message, runEndMarker):
+				// anything logged after a run ended is not part of a run
+				results = append(results, pending...)
+				pending = []munkiLogEntry{entry}
+			case strings.HasPrefix(entry.Message, runStartMarker):
+				pending = append(pending, entry)
+				for i := range pending {
+					pending[i].RunID = entry.Time.Unix()
+				}
+				results = append(results, pending...)
+				pending = nil
+				return stop(entry.Time)
+			default:
+				pending = append(pending, entry)
+			}
+			return false
+		}, &continuation)
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			break
+		}
+	}
+	results = append(results, pending...)
+
+	for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+		results[i], results[j] = results[j], results[i]
+	}
+	return results, nil
+}
+
+// readMunkiLogFile calls handle with each timestamped entry of the file, last
+// to first, together with the untimestamped lines that followed it. Such lines
+// at the start of a file carry over to the last entry of the next file through
+// continuation. It returns true if handle asked to stop.
+func readMunkiLogFile(path string, handle func(munkiLogEntry, []string) bool, continuation *[]string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "open Munki log")
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("error closing Munki log: %s", err)
+		}
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, errors.Wrap(err, "stat Munki log")
+	}
+	reader, err := utils.NewReverseLineReader(file, info.Size())
+	if err != nil {
+		return false, errors.Wrap(err, "read Munki log")
+	}
+
+	for {
+		line, _, err := reader.ReadLine()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, errors.Wrap(err, "read Munki log")
+		}
+
+		match := logLineRegex.FindStringSubmatch(line)
+		if match == nil {
+			*continuation = append(*continuation, line)
+			continue
+		}
+		t, err := time.Parse(logTimestampLayout, match[1])
+		if err != nil {
+			*continuation = append(*continuation, line)
+			continue
+		}
+
+		entry := munkiLogEntry{Time: t, Level: logLevel(match[2]), Message: match[2], Path: path}
+		lines := *continuation
+		*continuation = nil
+		if handle(entry, lines) {
+			return true, nil
+		}
+	}
+}
+
+func logLevel(message string) string {
+	switch {
+	case strings.HasPrefix(message, "ERROR"):
+		return "error"
+	case strings.HasPrefix(message, "WARNING"):
+		return "warning"
+	case strings.HasPrefix(message, "DEBUG"):
+		return "debug"
+	default:
+		return "info"
+	}
+}
@@ -0,0 +1,143 @@
+package munki
+
+import (
+	"context"
+	_ "embed"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed test_ManagedSoftwareUpdate.log
+var testManagedSoftwareUpdateLog []byte
+
+//go:embed test_ManagedSoftwareUpdate.log.1
+var testManagedSoftwareUpdateLogRotated []byte
+
+// setupLogs writes the fixture log and its rotated sibling to the LogFile
+// location set by testReportPath and returns the log path.
+func setupLogs(t *testing.T) string {
+	t.Helper()
+	reportPath := testReportPath(t)
+	logPath := filepath.Join(filepath.Dir(reportPath), "Logs", "ManagedSoftwareUpdate.log")
+	require.NoError(t, os.MkdirAll(filepath.Dir(logPath), 0755))
+	require.NoError(t, os.WriteFile(logPath, testManagedSoftwareUpdateLog, 0600))
+	require.NoError(t, os.WriteFile(logPath+".1", testManagedSoftwareUpdateLogRotated, 0600))
+	return logPath
+}
+
+func logConstraint(column, expression string) table.QueryContext {
+	return table.QueryContext{
+		Constraints: map[string]table.ConstraintList{
+			column: {
+				Constraints: []table.Constraint{
+					{Operator: table.OperatorEquals, Expression: expression},
+				},
+			},
+		},
+	}
+}
+
+func TestMunkiLogsGenerate(t *testing.T) {
+	logPath := setupLogs(t)
+
+	rows, err := MunkiLogsGenerate(context.Background(), table.QueryContext{})
+	require.NoError(t, err)
+	require.Len(t, rows, 11)
+
+	assert.Equal(t, map[string]string{
+		"timestamp": "1705226400",
+		"level":     "info",
+		"message":   "### Starting managedsoftwareupdate run: auto ###",
+		"run_id":    "1705226400",
+		"path":      logPath + ".1",
+		"since":     "",
+	}, rows[0])
+	assert.Equal(t, "warning", rows[2]["level"])
+
+	// the run continues across the rotated log
+	assert.Equal(t, "1705230000", rows[5]["run_id"])
+	assert.Equal(t, map[string]string{
+		"timestamp": "1705230002",
+		"level":     "error",
+		"message":   "ERROR: Install of Zoom failed:\n    installer: Package name is Zoom\n    installer: Installation failed",
+		"run_id":    "1705230000",
+		"path":      logPath,
+		"since":     "",
+	}, rows[6])
+	assert.Equal(t, "1705230000", rows[7]["run_id"])
+
+	// lines logged between runs have no run_id
+	assert.Equal(t, "Notifying Managed Software Center", rows[8]["message"])
+	assert.Equal(t, "", rows[8]["run_id"])
+
+	assert.Equal(t, "debug", rows[10]["level"])
+	assert.Equal(t, "1705233600", rows[10]["run_id"])
+}
+
+func TestMunkiLogsGenerateRunID(t *testing.T) {
+	setupLogs(t)
+
+	rows, err := MunkiLogsGenerate(context.Background(), logConstraint("run_id", "1705230000"))
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	for _, row := range rows {
+		assert.Equal(t, "1705230000", row["run_id"])
+	}
+}
+
+func TestMunkiLogsGenerateLevel(t *testing.T) {
+	setupLogs(t)
+
+	rows, err := MunkiLogsGenerate(context.Background(), logConstraint("level", "warning"))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "WARNING: Could not download Slack", rows[0]["message"])
+}
+
+func TestMunkiLogsGenerateSince(t *testing.T) {
+	setupLogs(t)
+
+	rows, err := MunkiLogsGenerate(context.Background(), logConstraint("since", "2024-01-14 11:00:20 +0000"))
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, "### Ending managedsoftwareupdate run ###", rows[0]["message"])
+	assert.Equal(t, "1705230000", rows[0]["run_id"])
+	assert.Equal(t, "2024-01-14 11:00:20 +0000", rows[0]["since"])
+
+	_, err = MunkiLogsGenerate(context.Background(), logConstraint("since", "yesterday"))
+	assert.Error(t, err)
+}
+
+func TestReadMunkiLogsStops(t *testing.T) {
+	logPath := setupLogs(t)
+
+	var starts []time.Time
+	entries, err := readMunkiLogs([]string{logPath, logPath + ".1"}, func(start time.Time) bool {
+		starts = append(starts, start)
+		return true
+	})
+	require.NoError(t, err)
+
+	// only the last run was read
+	assert.Len(t, starts, 1)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "DEBUG1: Skipping Apple updates", entries[1].Message)
+}
+
+func TestLogFilePaths(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "ManagedSoftwareUpdate.log")
+	for _, name := range []string{"ManagedSoftwareUpdate.log.10", "ManagedSoftwareUpdate.log.2", "ManagedSoftwareUpdate.log.bak"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0600))
+	}
+
+	paths, err := logFilePaths(logPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{logPath, logPath + ".2", logPath + ".10"}, paths)
+}
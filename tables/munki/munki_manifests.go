@@ -0,0 +1,221 @@
+package munki
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/macadmins/osquery-extension/pkg/utils"
+	"github.com/micromdm/plist"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/pkg/errors"
+)
+
+// selfServeManifest is written to the manifests directory by Managed Software
+// Center and is not part of the include tree.
+const selfServeManifest = "SelfServeManifest"
+
+type munkiManifest struct {
+	Catalogs          []string          `plist:"catalogs"`
+	ManagedInstalls   []string          `plist:"managed_installs"`
+	ManagedUninstalls []string          `plist:"managed_uninstalls"`
+	ManagedUpdates    []string          `plist:"managed_updates"`
+	OptionalInstalls  []string          `plist:"optional_installs"`
+	IncludedManifests []string          `plist:"included_manifests"`
+	ConditionalItems  []conditionalItem `plist:"conditional_items"`
+}
+
+// conditionalItem is an entry of conditional_items. The condition is an
+// NSPredicate evaluated by Munki, so it is reported rather than evaluated.
+type conditionalItem struct {
+	Condition         string            `plist:"condition" json:"condition"`
+	ManagedInstalls   []string          `plist:"managed_installs" json:"managed_installs,omitempty"`
+	ManagedUninstalls []string          `plist:"managed_uninstalls" json:"managed_uninstalls,omitempty"`
+	ManagedUpdates    []string          `plist:"managed_updates" json:"managed_updates,omitempty"`
+	OptionalInstalls  []string          `plist:"optional_installs" json:"optional_installs,omitempty"`
+	IncludedManifests []string          `plist:"included_manifests" json:"included_manifests,omitempty"`
+	ConditionalItems  []conditionalItem `plist:"conditional_items" json:"conditional_items,omitempty"`
+}
+
+// manifestInclude is a manifest reached while walking the include tree.
+type manifestInclude struct {
+	Name      string
+	Parent    string
+	Condition string
+	Depth     int
+}
+
+func MunkiManifestsColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("name"),
+		table.TextColumn("parent"),
+		table.TextColumn("condition"),
+		table.IntegerColumn("depth"),
+		table.TextColumn("catalogs"),
+		table.TextColumn("managed_installs"),
+		table.TextColumn("managed_uninstalls"),
+		table.TextColumn("managed_updates"),
+		table.TextColumn("optional_installs"),
+		table.TextColumn("conditional_items"),
+		table.TextColumn("included_manifests"),
+		table.TextColumn("path"),
+	}
+}
+
+func MunkiManifestsGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	fs := utils.OSFileSystem{}
+	manifestDir := filepath.Join(managedInstallDir(fs), "manifests")
+
+	roots, err := manifestRoots(fs, manifestDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []map[string]string
+	visited := map[string]bool{}
+	queue := make([]manifestInclude, 0, len(roots))
+	for _, root := range roots {
+		queue = append(queue, manifestInclude{Name: root})
+	}
+
+	for len(queue) > 0 {
+		include := queue[0]
+		queue = queue[1:]
+		if visited[include.Name] {
+			continue
+		}
+		visited[include.Name] = true
+
+		path := filepath.Join(manifestDir, include.Name)
+		manifest, err := loadMunkiManifest(fs, path)
+		if err != nil {
+			return nil, err
+		}
+		if manifest == nil {
+			log.Printf("Munki manifest %s included by %q not found", include.Name, include.Parent)
+			continue
+		}
+
+		row, err := manifestRow(include, manifest, path)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, row)
+
+		for _, name := range manifest.IncludedManifests {
+			queue = append(queue, manifestInclude{Name: name, Parent: include.Name, Depth: include.Depth + 1})
+		}
+		queue = append(queue, conditionalIncludes(include, manifest.ConditionalItems, "")...)
+	}
+
+	return results, nil
+}
+
+// conditionalIncludes returns the manifests included from conditional_items,
+// joining the conditions of nested items with AND.
+func conditionalIncludes(parent manifestInclude, items []conditionalItem, outer string) []manifestInclude {
+	var includes []manifestInclude
+	for _, item := range items {
+		condition := item.Condition
+		if outer != "" {
+			condition = "(" + outer + ") AND (" + item.Condition + ")"
+		}
+		for _, name := range item.IncludedManifests {
+			includes = append(includes, manifestInclude{
+				Name:      name,
+				Parent:    parent.Name,
+				Condition: condition,
+				Depth:     parent.Depth + 1,
+			})
+		}
+		includes = append(includes, conditionalIncludes(parent, item.ConditionalItems, condition)...)
+	}
+	return includes
+}
+
+func manifestRow(include manifestInclude, manifest *munkiManifest, path string) (map[string]string, error) {
+	conditionalItems := ""
+	if len(manifest.ConditionalItems) > 0 {
+		jsonStr, err := json.Marshal(manifest.ConditionalItems)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshal conditional_items")
+		}
+		conditionalItems = string(jsonStr)
+	}
+
+	return map[string]string{
+		"name":               include.Name,
+		"parent":             include.Parent,
+		"condition":          include.Condition,
+		"depth":              strconv.Itoa(include.Depth),
+		"catalogs":           strings.Join(manifest.Catalogs, ";"),
+		"managed_installs":   strings.Join(manifest.ManagedInstalls, ";"),
+		"managed_uninstalls": strings.Join(manifest.ManagedUninstalls, ";"),
+		"managed_updates":    strings.Join(manifest.ManagedUpdates, ";"),
+		"optional_installs":  strings.Join(manifest.OptionalInstalls, ";"),
+		"conditional_items":  conditionalItems,
+		"included_manifests": strings.Join(manifest.IncludedManifests, ";"),
+		"path":               path,
+	}, nil
+}
+
+// manifestRoots returns the primary manifest named in the last report. When it
+// is unknown, every cached manifest is treated as a root.
+func manifestRoots(fsys utils.FileSystem, manifestDir string) ([]string, error) {
+	report, err := loadMunkiReport(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if report != nil && report.ManifestName != "" && utils.FileExists(fsys, filepath.Join(manifestDir, report.ManifestName)) {
+		return []string{report.ManifestName}, nil
+	}
+
+	var roots []string
+	err = filepath.WalkDir(manifestDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		name, err := filepath.Rel(manifestDir, path)
+		if err != nil {
+			return err
+		}
+		if name != selfServeManifest {
+			roots = append(roots, filepath.ToSlash(name))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "list Munki manifests")
+	}
+	sort.Strings(roots)
+
+	return roots, nil
+}
+
+func loadMunkiManifest(fs utils.FileSystem, path string) (*munkiManifest, error) {
+	if !utils.FileExists(fs, path) {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read Munki manifest")
+	}
+
+	var manifest munkiManifest
+	if err := plist.Unmarshal(data, &manifest); err != nil {
+		return nil, errors.Wrapf(err, "decode Munki manifest %s", path)
+	}
+	return &manifest, nil
+}
@@ -0,0 +1,179 @@
+package munki
+
+import (
+	"context"
+	_ "embed"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed test_manifest_site_default.plist
+var testManifestSiteDefault []byte
+
+//go:embed test_manifest_browsers.plist
+var testManifestBrowsers []byte
+
+//go:embed test_catalog_production.plist
+var testCatalogProduction []byte
+
+const testClientManifest = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>catalogs</key>
+	<array>
+		<string>production</string>
+	</array>
+	<key>included_manifests</key>
+	<array>
+		<string>site_default</string>
+	</array>
+</dict>
+</plist>`
+
+// setupManifests writes the fixture manifests below a temporary
+// ManagedInstallDir and returns its manifests directory.
+func setupManifests(t *testing.T) string {
+	t.Helper()
+	reportPath := testReportPath(t)
+	manifestDir := filepath.Join(filepath.Dir(reportPath), "manifests")
+	files := map[string][]byte{
+		"test-manifest":     []byte(testClientManifest),
+		"site_default":      testManifestSiteDefault,
+		"includes/browsers": testManifestBrowsers,
+		selfServeManifest:   []byte(`<plist version="1.0"><dict/></plist>`),
+	}
+	for name, data := range files {
+		path := filepath.Join(manifestDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, data, 0600))
+	}
+	return manifestDir
+}
+
+func TestMunkiManifestsGenerate(t *testing.T) {
+	manifestDir := setupManifests(t)
+	reportPath := filepath.Join(filepath.Dir(manifestDir), "ManagedInstallReport.plist")
+	require.NoError(t, os.WriteFile(reportPath, testManagedInstallReportMunki7, 0600))
+
+	rows, err := MunkiManifestsGenerate(context.Background(), table.QueryContext{})
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	assert.Equal(t, "test-manifest", rows[0]["name"])
+	assert.Equal(t, "", rows[0]["parent"])
+	assert.Equal(t, "0", rows[0]["depth"])
+
+	assert.Equal(t, map[string]string{
+		"name":               "site_default",
+		"parent":             "test-manifest",
+		"condition":          "",
+		"depth":              "1",
+		"catalogs":           "production",
+		"managed_installs":   "munkitools;osquery",
+		"managed_uninstalls": "Zoom",
+		"managed_updates":    "",
+		"optional_installs":  "Slack",
+		"conditional_items":  `[{"condition":"machine_type == \"laptop\"","managed_installs":["Nudge"],"included_manifests":["includes/laptops"]}]`,
+		"included_manifests": "includes/browsers",
+		"path":               filepath.Join(manifestDir, "site_default"),
+	}, rows[1])
+
+	// the include loop back to site_default is not followed twice, and the
+	// missing conditional include is skipped
+	assert.Equal(t, "includes/browsers", rows[2]["name"])
+	assert.Equal(t, "site_default", rows[2]["parent"])
+	assert.Equal(t, "2", rows[2]["depth"])
+	assert.Equal(t, "GoogleChrome;Firefox", rows[2]["managed_installs"])
+}
+
+func TestMunkiManifestsGenerateWithoutReport(t *testing.T) {
+	setupManifests(t)
+
+	rows, err := MunkiManifestsGenerate(context.Background(), table.QueryContext{})
+	require.NoError(t, err)
+
+	names := map[string]bool{}
+	for _, row := range rows {
+		names[row["name"]] = true
+	}
+	assert.Equal(t, map[string]bool{"test-manifest": true, "site_default": true, "includes/browsers": true}, names)
+}
+
+func TestConditionalIncludes(t *testing.T) {
+	items := []conditionalItem{
+		{
+			Condition: `machine_type == "laptop"`,
+			ConditionalItems: []conditionalItem{
+				{Condition: `arch == "arm64"`, IncludedManifests: []string{"includes/apple_silicon"}},
+			},
+		},
+	}
+	includes := conditionalIncludes(manifestInclude{Name: "site_default", Depth: 1}, items, "")
+	assert.Equal(t, []manifestInclude{
+		{
+			Name:      "includes/apple_silicon",
+			Parent:    "site_default",
+			Condition: `(machine_type == "laptop") AND (arch == "arm64")`,
+			Depth:     2,
+		},
+	}, includes)
+}
+
+func TestMunkiCatalogItemsGenerate(t *testing.T) {
+	reportPath := testReportPath(t)
+	catalogDir := filepath.Join(filepath.Dir(reportPath), "catalogs")
+	require.NoError(t, os.MkdirAll(catalogDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(catalogDir, "production"), testCatalogProduction, 0600))
+
+	rows, err := MunkiCatalogItemsGenerate(context.Background(), table.QueryContext{})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	assert.Equal(t, map[string]string{
+		"catalog":                 "production",
+		"name":                    "GoogleChrome",
+		"display_name":            "Google Chrome",
+		"version":                 "120.0.6099.216",
+		"catalogs":                "testing;production",
+		"minimum_os_version":      "10.15",
+		"maximum_os_version":      "",
+		"supported_architectures": "",
+		"blocking_applications":   "Google Chrome.app",
+		"requires":                "",
+		"update_for":              "",
+		"installer_type":          "copy_from_dmg",
+		"installer_item_size":     "225780",
+		"uninstallable":           "true",
+		"unattended_install":      "true",
+		"installs":                `[{"CFBundleShortVersionString":"120.0.6099.216","path":"/Applications/Google Chrome.app","type":"application"}]`,
+	}, rows[0])
+	assert.Equal(t, "munkitools_core", rows[1]["requires"])
+
+	queryContext := table.QueryContext{
+		Constraints: map[string]table.ConstraintList{
+			"name": {
+				Constraints: []table.Constraint{
+					{Operator: table.OperatorEquals, Expression: "munkitools"},
+				},
+			},
+		},
+	}
+	rows, err = MunkiCatalogItemsGenerate(context.Background(), queryContext)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "munkitools", rows[0]["name"])
+}
+
+func TestMunkiCatalogItemsGenerateNoCatalogs(t *testing.T) {
+	testReportPath(t)
+
+	rows, err := MunkiCatalogItemsGenerate(context.Background(), table.QueryContext{})
+	assert.NoError(t, err)
+	assert.Empty(t, rows)
+}
@@ -0,0 +1,118 @@
+package munki
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/macadmins/osquery-extension/pkg/utils"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+// pendingItem is an entry of ItemsToInstall, ItemsToRemove, AppleUpdates or
+// ProblemInstalls.
+type pendingItem struct {
+	Name                  string      `plist:"name"`
+	DisplayName           string      `plist:"display_name"`
+	VersionToInstall      string      `plist:"version_to_install"`
+	InstalledVersion      string      `plist:"installed_version"`
+	InstallerItemSize     munkiNumber `plist:"installer_item_size"`
+	InstalledSize         munkiNumber `plist:"installed_size"`
+	RestartAction         string      `plist:"RestartAction"`
+	ForceInstallAfterDate MunkiDate   `plist:"force_install_after_date"`
+	UnattendedInstall     bool        `plist:"unattended_install"`
+	UnattendedUninstall   bool        `plist:"unattended_uninstall"`
+	BlockingApplications  []string    `plist:"blocking_applications"`
+	Note                  string      `plist:"note"`
+}
+
+// UnmarshalPlist accepts both item dictionaries and bare item names, which
+// older Munki versions write to ProblemInstalls.
+func (p *pendingItem) UnmarshalPlist(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		*p = pendingItem{Name: name}
+		return nil
+	}
+
+	// decode through an alias so this method is not called recursively
+	type item pendingItem
+	var i item
+	if err := unmarshal(&i); err != nil {
+		return err
+	}
+	*p = pendingItem(i)
+	return nil
+}
+
+func MunkiPendingItemsColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("type"),
+		table.TextColumn("name"),
+		table.TextColumn("display_name"),
+		table.TextColumn("version_to_install"),
+		table.TextColumn("installed_version"),
+		table.BigIntColumn("installer_item_size"),
+		table.BigIntColumn("installed_size"),
+		table.TextColumn("restart_action"),
+		table.TextColumn("force_install_after_date"),
+		table.TextColumn("force_install_overdue"),
+		table.TextColumn("unattended"),
+		table.TextColumn("blocking_applications"),
+		table.TextColumn("note"),
+		table.TextColumn("end_time"),
+	}
+}
+
+func MunkiPendingItemsGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	fs := utils.OSFileSystem{}
+	report, err := loadMunkiReport(fs)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil {
+		return nil, nil
+	}
+
+	return buildPendingItemsOutput(report, time.Now()), nil
+}
+
+func buildPendingItemsOutput(report *munkiReport, now time.Time) []map[string]string {
+	var results []map[string]string
+	for _, item := range report.ItemsToInstall {
+		results = append(results, pendingItemRow("install", item, item.UnattendedInstall, report.EndTime, now))
+	}
+	for _, item := range report.ItemsToRemove {
+		results = append(results, pendingItemRow("removal", item, item.UnattendedUninstall, report.EndTime, now))
+	}
+	for _, item := range report.AppleUpdates {
+		results = append(results, pendingItemRow("apple_update", item, item.UnattendedInstall, report.EndTime, now))
+	}
+	for _, item := range report.ProblemInstalls {
+		results = append(results, pendingItemRow("problem", item, item.UnattendedInstall, report.EndTime, now))
+	}
+	return results
+}
+
+func pendingItemRow(itemType string, item pendingItem, unattended bool, endTime MunkiDate, now time.Time) map[string]string {
+	deadline := time.Time(item.ForceInstallAfterDate)
+	overdue := !deadline.IsZero() && now.After(deadline)
+
+	return map[string]string{
+		"type":                     itemType,
+		"name":                     item.Name,
+		"display_name":             item.DisplayName,
+		"version_to_install":       item.VersionToInstall,
+		"installed_version":        item.InstalledVersion,
+		"installer_item_size":      item.InstallerItemSize.String(),
+		"installed_size":           item.InstalledSize.String(),
+		"restart_action":           item.RestartAction,
+		"force_install_after_date": item.ForceInstallAfterDate.String(),
+		"force_install_overdue":    fmt.Sprintf("%v", overdue),
+		"unattended":               fmt.Sprintf("%v", unattended),
+		"blocking_applications":    strings.Join(item.BlockingApplications, ";"),
+		"note":                     item.Note,
+		"end_time":                 endTime.String(),
+	}
+}
@@ -0,0 +1,100 @@
+package munki
+
+import (
+	"context"
+	_ "embed"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/macadmins/osquery-extension/pkg/utils"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed test_ManagedInstallReport_with_items.plist
+var testManagedInstallReportWithItems []byte
+
+func TestBuildPendingItemsOutput(t *testing.T) {
+	reportPath := testReportPath(t)
+	require.NoError(t, os.WriteFile(reportPath, testManagedInstallReportWithItems, 0600))
+
+	report, err := loadMunkiReport(utils.OSFileSystem{})
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 14, 12, 0, 0, 0, time.UTC)
+	rows := buildPendingItemsOutput(report, now)
+	require.Len(t, rows, 5)
+
+	assert.Equal(t, map[string]string{
+		"type":                     "install",
+		"name":                     "Slack",
+		"display_name":             "Slack",
+		"version_to_install":       "4.47.72",
+		"installed_version":        "",
+		"installer_item_size":      "120345",
+		"installed_size":           "250000",
+		"restart_action":           "None",
+		"force_install_after_date": "2024-01-10 17:00:00 +0000",
+		"force_install_overdue":    "true",
+		"unattended":               "false",
+		"blocking_applications":    "Slack.app;Slack Helper.app",
+		"note":                     "",
+		"end_time":                 "2024-01-14 12:00:00 +0000",
+	}, rows[0])
+
+	assert.Equal(t, "1Password", rows[1]["name"])
+	assert.Equal(t, "false", rows[1]["force_install_overdue"])
+	assert.Equal(t, "true", rows[1]["unattended"])
+
+	assert.Equal(t, "removal", rows[2]["type"])
+	assert.Equal(t, "Zoom", rows[2]["name"])
+	assert.Equal(t, "5.17.5", rows[2]["installed_version"])
+	assert.Equal(t, "true", rows[2]["unattended"])
+	assert.Equal(t, "", rows[2]["force_install_after_date"])
+	assert.Equal(t, "false", rows[2]["force_install_overdue"])
+
+	assert.Equal(t, "apple_update", rows[3]["type"])
+	assert.Equal(t, "RequireRestart", rows[3]["restart_action"])
+
+	assert.Equal(t, "problem", rows[4]["type"])
+	assert.Equal(t, "Integrity check failed", rows[4]["note"])
+}
+
+func TestMunkiPendingItemsGenerateNoItems(t *testing.T) {
+	reportPath := testReportPath(t)
+	require.NoError(t, os.WriteFile(reportPath, testManagedInstallReport, 0600))
+
+	rows, err := MunkiPendingItemsGenerate(context.Background(), table.QueryContext{})
+	assert.NoError(t, err)
+	assert.Empty(t, rows)
+}
+
+func TestMunkiInfoGenerateProblemInstalls(t *testing.T) {
+	reportPath := testReportPath(t)
+	require.NoError(t, os.WriteFile(reportPath, testManagedInstallReportWithItems, 0600))
+
+	rows, err := MunkiInfoGenerate(context.Background(), table.QueryContext{})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "Firefox", rows[0]["problem_installs"])
+}
+
+func TestPendingItemUnmarshalName(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>ProblemInstalls</key>
+	<array>
+		<string>Firefox</string>
+	</array>
+</dict>
+</plist>`)
+	reportPath := testReportPath(t)
+	require.NoError(t, os.WriteFile(reportPath, data, 0600))
+
+	report, err := loadMunkiReport(utils.OSFileSystem{})
+	require.NoError(t, err)
+	assert.Equal(t, []pendingItem{{Name: "Firefox"}}, report.ProblemInstalls)
+}
@@ -0,0 +1,222 @@
+package munki
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/macadmins/osquery-extension/pkg/utils"
+	"github.com/micromdm/plist"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultManagedInstallDir = "/Library/Managed Installs"
+	redactedValue            = "<redacted>"
+)
+
+type preferenceSource struct {
+	Path    string
+	Managed bool
+}
+
+// preferenceSources lists the Munki preference files from highest to lowest
+// precedence. Managed (profile) preferences override local ones, as they do
+// for CFPreferences, and managedsoftwareupdate runs as root so reads root's
+// preferences before the system ones. It is defined as a global variable to
+// ease testing.
+var preferenceSources = []preferenceSource{
+	{Path: "/Library/Managed Preferences/ManagedInstalls.plist", Managed: true},
+	{Path: "/var/root/Library/Preferences/ManagedInstalls.plist", Managed: false},
+	{Path: "/Library/Preferences/ManagedInstalls.plist", Managed: false},
+}
+
+// defaultPreferences are the Munki defaults for the preferences the tables in
+// this package rely on.
+var defaultPreferences = map[string]interface{}{
+	"ManagedInstallDir": defaultManagedInstallDir,
+	"LogFile":           "/Library/Managed Installs/Logs/ManagedSoftwareUpdate.log",
+	"SoftwareRepoURL":   "http://munki/repo",
+}
+
+// redactedPreferences are never returned as they carry credentials.
+var redactedPreferences = map[string]bool{
+	"AdditionalHttpHeaders": true,
+}
+
+type munkiPreference struct {
+	Key     string
+	Value   interface{}
+	Source  string
+	Managed bool
+}
+
+func MunkiPreferencesColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("key"),
+		table.TextColumn("value"),
+		table.TextColumn("source"),
+		table.TextColumn("managed"),
+	}
+}
+
+func MunkiPreferencesGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	fs := utils.OSFileSystem{}
+	prefs, err := loadMunkiPreferences(fs)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []map[string]string
+	for _, pref := range prefs {
+		value, err := preferenceString(pref.Key, pref.Value)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, map[string]string{
+			"key":     pref.Key,
+			"value":   value,
+			"source":  pref.Source,
+			"managed": fmt.Sprintf("%v", pref.Managed),
+		})
+	}
+
+	return results, nil
+}
+
+// loadMunkiPreferences returns the effective value of every preference set in
+// the preference files or defaulted by Munki, sorted by key.
+func loadMunkiPreferences(fs utils.FileSystem) ([]munkiPreference, error) {
+	effective := map[string]munkiPreference{}
+	for key, value := range defaultPreferences {
+		effective[key] = munkiPreference{Key: key, Value: value, Source: "default"}
+	}
+
+	// apply from lowest to highest precedence so later files win
+	for i := len(preferenceSources) - 1; i >= 0; i-- {
+		source := preferenceSources[i]
+		values, err := loadPreferenceFile(fs, source.Path)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range values {
+			effective[key] = munkiPreference{
+				Key:     key,
+				Value:   value,
+				Source:  source.Path,
+				Managed: source.Managed,
+			}
+		}
+	}
+
+	prefs := make([]munkiPreference, 0, len(effective))
+	for _, pref := range effective {
+		prefs = append(prefs, pref)
+	}
+	sort.Slice(prefs, func(i, j int) bool { return prefs[i].Key < prefs[j].Key })
+
+	return prefs, nil
+}
+
+func loadPreferenceFile(fs utils.FileSystem, path string) (map[string]interface{}, error) {
+	if !utils.FileExists(fs, path) {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read ManagedInstalls preferences")
+	}
+
+	var values map[string]interface{}
+	if err := plist.Unmarshal(data, &values); err != nil {
+		return nil, errors.Wrapf(err, "decode ManagedInstalls preferences %s", path)
+	}
+	return values, nil
+}
+
+// munkiPreferenceString returns the effective value of a string preference.
+func munkiPreferenceString(fs utils.FileSystem, key string) string {
+	prefs, err := loadMunkiPreferences(fs)
+	if err != nil {
+		log.Printf("load Munki preferences: %v", err)
+		if value, ok := defaultPreferences[key].(string); ok {
+			return value
+		}
+		return ""
+	}
+	for _, pref := range prefs {
+		if pref.Key == key {
+			if value, ok := pref.Value.(string); ok {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// managedInstallDir returns the resolved ManagedInstallDir, which holds the
+// reports, manifests and catalogs read by the Munki tables.
+func managedInstallDir(fs utils.FileSystem) string {
+	if dir := munkiPreferenceString(fs, "ManagedInstallDir"); dir != "" {
+		return dir
+	}
+	return defaultManagedInstallDir
+}
+
+func reportFilePath(fs utils.FileSystem) string {
+	return filepath.Join(managedInstallDir(fs), "ManagedInstallReport.plist")
+}
+
+func isSecretPreference(key string) bool {
+	if redactedPreferences[key] {
+		return true
+	}
+	lower := strings.ToLower(key)
+	for _, word := range []string{"password", "secret", "token", "passphrase"} {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// preferenceString renders a preference value for output, redacting secrets.
+func preferenceString(key string, value interface{}) (string, error) {
+	if isSecretPreference(key) {
+		return redactedValue, nil
+	}
+	if _, ok := value.([]byte); ok {
+		// data values hold certificates and keys
+		return redactedValue, nil
+	}
+	return plistValueString(value)
+}
+
+// plistValueString renders a decoded plist value for output. Arrays and
+// dictionaries are serialized as JSON.
+func plistValueString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case time.Time:
+		return v.UTC().Format("2006-01-02 15:04:05 +0000"), nil
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), nil
+	case map[string]interface{}, []interface{}:
+		jsonStr, err := json.Marshal(v)
+		if err != nil {
+			return "", errors.Wrap(err, "marshal to json string")
+		}
+		return string(jsonStr), nil
+	default:
+		return fmt.Sprintf("%v", v), nil
+	}
+}
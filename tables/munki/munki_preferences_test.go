@@ -0,0 +1,162 @@
+package munki
+
+import (
+	"context"
+	_ "embed"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/macadmins/osquery-extension/pkg/utils"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed test_ManagedInstalls.plist
+var testManagedInstalls []byte
+
+//go:embed test_ManagedInstalls_managed.plist
+var testManagedInstallsManaged []byte
+
+const testRootManagedInstalls = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>ClientIdentifier</key>
+	<string>root-manifest</string>
+	<key>SoftwareRepoURL</key>
+	<string>https://munki.example.com/repo</string>
+</dict>
+</plist>
+`
+
+func setupPreferences(t *testing.T, managed, local []byte) (string, string) {
+	t.Helper()
+	managedPath, _, localPath := setupAllPreferences(t, managed, nil, local)
+	return managedPath, localPath
+}
+
+// setupAllPreferences also writes root's preferences, which are between the
+// managed and local ones.
+func setupAllPreferences(t *testing.T, managed, root, local []byte) (string, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	managedPath := filepath.Join(dir, "Managed Preferences", "ManagedInstalls.plist")
+	rootPath := filepath.Join(dir, "var", "root", "Preferences", "ManagedInstalls.plist")
+	localPath := filepath.Join(dir, "Preferences", "ManagedInstalls.plist")
+	for path, data := range map[string][]byte{managedPath: managed, rootPath: root, localPath: local} {
+		if data == nil {
+			continue
+		}
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, data, 0600))
+	}
+
+	original := preferenceSources
+	preferenceSources = []preferenceSource{
+		{Path: managedPath, Managed: true},
+		{Path: rootPath, Managed: false},
+		{Path: localPath, Managed: false},
+	}
+	t.Cleanup(func() { preferenceSources = original })
+
+	return managedPath, rootPath, localPath
+}
+
+func TestMunkiPreferencesGenerate(t *testing.T) {
+	managedPath, localPath := setupPreferences(t, testManagedInstallsManaged, testManagedInstalls)
+
+	rows, err := MunkiPreferencesGenerate(context.Background(), table.QueryContext{})
+	require.NoError(t, err)
+
+	byKey := map[string]map[string]string{}
+	for _, row := range rows {
+		byKey[row["key"]] = row
+	}
+
+	assert.Equal(t, map[string]string{
+		"key":     "ClientIdentifier",
+		"value":   "managed-manifest",
+		"source":  managedPath,
+		"managed": "true",
+	}, byKey["ClientIdentifier"])
+	assert.Equal(t, "3", byKey["LoggingLevel"]["value"])
+	assert.Equal(t, "true", byKey["InstallAppleSoftwareUpdates"]["value"])
+	assert.Equal(t, localPath, byKey["InstallAppleSoftwareUpdates"]["source"])
+	assert.Equal(t, "false", byKey["InstallAppleSoftwareUpdates"]["managed"])
+	assert.Equal(t, "/usr/local/munki_data", byKey["ManagedInstallDir"]["value"])
+
+	// secrets are redacted
+	assert.Equal(t, redactedValue, byKey["AdditionalHttpHeaders"]["value"])
+	assert.Equal(t, redactedValue, byKey["ClientCertificatePassword"]["value"])
+
+	// defaults fill in unset preferences
+	assert.Equal(t, "default", byKey["LogFile"]["source"])
+	assert.Equal(t, "/Library/Managed Installs/Logs/ManagedSoftwareUpdate.log", byKey["LogFile"]["value"])
+}
+
+func TestMunkiPreferencesRootPreferences(t *testing.T) {
+	managedPath, rootPath, localPath := setupAllPreferences(t, testManagedInstallsManaged, []byte(testRootManagedInstalls), testManagedInstalls)
+
+	rows, err := MunkiPreferencesGenerate(context.Background(), table.QueryContext{})
+	require.NoError(t, err)
+
+	byKey := map[string]map[string]string{}
+	for _, row := range rows {
+		byKey[row["key"]] = row
+	}
+
+	// managed preferences override root's
+	assert.Equal(t, "managed-manifest", byKey["ClientIdentifier"]["value"])
+	assert.Equal(t, managedPath, byKey["ClientIdentifier"]["source"])
+
+	// root's preferences override the system ones
+	assert.Equal(t, "https://munki.example.com/repo", byKey["SoftwareRepoURL"]["value"])
+	assert.Equal(t, rootPath, byKey["SoftwareRepoURL"]["source"])
+	assert.Equal(t, "false", byKey["SoftwareRepoURL"]["managed"])
+
+	assert.Equal(t, localPath, byKey["InstallAppleSoftwareUpdates"]["source"])
+}
+
+func TestManagedInstallDir(t *testing.T) {
+	fs := utils.OSFileSystem{}
+
+	setupPreferences(t, nil, nil)
+	assert.Equal(t, defaultManagedInstallDir, managedInstallDir(fs))
+	assert.Equal(t, "/Library/Managed Installs/ManagedInstallReport.plist", reportFilePath(fs))
+
+	setupPreferences(t, testManagedInstallsManaged, testManagedInstalls)
+	assert.Equal(t, "/usr/local/munki_data", managedInstallDir(fs))
+	assert.Equal(t, "/usr/local/munki_data/ManagedInstallReport.plist", reportFilePath(fs))
+}
+
+func TestManagedInstallDirInvalidPreferences(t *testing.T) {
+	setupPreferences(t, []byte("not a plist"), nil)
+	assert.Equal(t, defaultManagedInstallDir, managedInstallDir(utils.OSFileSystem{}))
+}
+
+func TestPreferenceString(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value interface{}
+		want  string
+	}{
+		{"string", "SoftwareRepoURL", "https://munki.example.com/repo", "https://munki.example.com/repo"},
+		{"bool", "SuppressUserNotification", true, "true"},
+		{"integer", "DaysBetweenNotifications", uint64(1), "1"},
+		{"array", "LocalOnlyManifest", []interface{}{"a", "b"}, `["a","b"]`},
+		{"header", "AdditionalHttpHeaders", []interface{}{"Authorization: Basic Zm9v"}, redactedValue},
+		{"token", "MunkiReportToken", "abc", redactedValue},
+		{"data", "ClientCertificate", []byte("cert"), redactedValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := preferenceString(tt.key, tt.value)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package munki
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/macadmins/osquery-extension/pkg/utils"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/pkg/errors"
+)
+
+// Munki archives the previous report as ManagedInstallReport-<timestamp>.plist
+// at the start of each run, using the local time of the archive.
+const archiveTimestampLayout = "2006-01-02-150405"
+
+func MunkiRunHistoryColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("start_time"),
+		table.TextColumn("end_time"),
+		table.IntegerColumn("duration_seconds"),
+		table.TextColumn("success"),
+		table.IntegerColumn("errors_count"),
+		table.IntegerColumn("warnings_count"),
+		table.TextColumn("console_user"),
+		table.TextColumn("manifest_name"),
+		table.TextColumn("run_type"),
+		table.TextColumn("version"),
+		table.TextColumn("archived"),
+		table.TextColumn("path"),
+	}
+}
+
+func MunkiRunHistoryGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	fs := utils.OSFileSystem{}
+	since, err := utils.TimeLowerBound(queryContext, "start_time")
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := archivedReportPaths(filepath.Join(managedInstallDir(fs), "Archives"), since)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []map[string]string
+	for _, path := range paths {
+		report, err := loadMunkiReportFile(fs, path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "load archived report %s", path)
+		}
+		if report == nil {
+			continue
+		}
+		results = append(results, runHistoryRow(report, path, true))
+	}
+
+	report, err := loadMunkiReport(fs)
+	if err != nil {
+		return nil, err
+	}
+	if report != nil {
+		results = append(results, runHistoryRow(report, reportFilePath(fs), false))
+	}
+
+	return results, nil
+}
+
+func runHistoryRow(report *munkiReport, path string, archived bool) map[string]string {
+	duration := ""
+	start, end := time.Time(report.StartTime), time.Time(report.EndTime)
+	if !start.IsZero() && !end.IsZero() {
+		duration = strconv.Itoa(int(end.Sub(start).Seconds()))
+	}
+
+	return map[string]string{
+		"start_time":       report.StartTime.String(),
+		"end_time":         report.EndTime.String(),
+		"duration_seconds": duration,
+		"success":          fmt.Sprintf("%v", len(report.Errors) == 0),
+		"errors_count":     strconv.Itoa(len(report.Errors)),
+		"warnings_count":   strconv.Itoa(len(report.Warnings)),
+		"console_user":     report.ConsoleUser,
+		"manifest_name":    report.ManifestName,
+		"run_type":         report.RunType,
+		"version":          report.ManagedInstallVersion,
+		"archived":         fmt.Sprintf("%v", archived),
+		"path":             path,
+	}
+}
+
+// archivedReportPaths returns the archived reports in dir, oldest first. A report
+// is archived after its run ended, so reports archived before since cannot have
+// started after it and are skipped without being read.
+func archivedReportPaths(dir string, since time.Time) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "read Munki Archives directory")
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "ManagedInstallReport-") || !strings.HasSuffix(name, ".plist") {
+			continue
+		}
+		if !since.IsZero() {
+			stamp := strings.TrimSuffix(strings.TrimPrefix(name, "ManagedInstallReport-"), ".plist")
+			archived, err := time.ParseInLocation(archiveTimestampLayout, stamp, time.Local)
+			if err == nil && archived.Before(since) {
+				continue
+			}
+		}
+		paths = append(paths, filepath.Join(dir, name))
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
@@ -0,0 +1,89 @@
+package munki
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRunHistory(t *testing.T) string {
+	t.Helper()
+	reportPath := testReportPath(t)
+	dir := filepath.Dir(reportPath)
+	require.NoError(t, os.WriteFile(reportPath, testManagedInstallReportMunki7, 0600))
+
+	archives := filepath.Join(dir, "Archives")
+	require.NoError(t, os.Mkdir(archives, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(archives, "ManagedInstallReport-2022-09-22-130000.plist"), testManagedInstallReport, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(archives, "ManagedInstallReport-2024-01-14-130000.plist"), testManagedInstallReportWithResults, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(archives, "notes.txt"), []byte("ignored"), 0600))
+	return dir
+}
+
+func TestMunkiRunHistoryGenerate(t *testing.T) {
+	dir := setupRunHistory(t)
+
+	rows, err := MunkiRunHistoryGenerate(context.Background(), table.QueryContext{})
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	assert.Equal(t, map[string]string{
+		"start_time":       "2022-09-22 11:52:43 +0000",
+		"end_time":         "2022-09-22 11:53:01 +0000",
+		"duration_seconds": "18",
+		"success":          "true",
+		"errors_count":     "0",
+		"warnings_count":   "1",
+		"console_user":     "Foo",
+		"manifest_name":    "e388bb34-ea80-49e2-8d79-da164f8bf9af",
+		"run_type":         "auto",
+		"version":          "5.6.4.4406",
+		"archived":         "true",
+		"path":             filepath.Join(dir, "Archives", "ManagedInstallReport-2022-09-22-130000.plist"),
+	}, rows[0])
+
+	assert.Equal(t, "2024-01-14 11:58:00 +0000", rows[1]["start_time"])
+	assert.Equal(t, "false", rows[1]["success"])
+	assert.Equal(t, "1", rows[1]["errors_count"])
+	assert.Equal(t, "120", rows[1]["duration_seconds"])
+
+	assert.Equal(t, "2025-07-28 20:08:30 +0000", rows[2]["start_time"])
+	assert.Equal(t, "88", rows[2]["duration_seconds"])
+	assert.Equal(t, "false", rows[2]["archived"])
+	assert.Equal(t, filepath.Join(dir, "ManagedInstallReport.plist"), rows[2]["path"])
+}
+
+func TestMunkiRunHistoryGenerateStartTimeConstraint(t *testing.T) {
+	setupRunHistory(t)
+
+	queryContext := table.QueryContext{
+		Constraints: map[string]table.ConstraintList{
+			"start_time": {
+				Constraints: []table.Constraint{
+					{
+						Operator:   table.OperatorGreaterThan,
+						Expression: "2023-01-01",
+					},
+				},
+			},
+		},
+	}
+	rows, err := MunkiRunHistoryGenerate(context.Background(), queryContext)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "2024-01-14 11:58:00 +0000", rows[0]["start_time"])
+	assert.Equal(t, "2025-07-28 20:08:30 +0000", rows[1]["start_time"])
+}
+
+func TestMunkiRunHistoryGenerateNoArchives(t *testing.T) {
+	testReportPath(t)
+
+	rows, err := MunkiRunHistoryGenerate(context.Background(), table.QueryContext{})
+	assert.NoError(t, err)
+	assert.Empty(t, rows)
+}
@@ -0,0 +1,43 @@
+package munki
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/macadmins/osquery-extension/pkg/utils"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+func MunkiSelfServiceColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("name"),
+		table.TextColumn("action"),
+	}
+}
+
+func MunkiSelfServiceGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	fs := utils.OSFileSystem{}
+	// Managed Software Center saves choices to /Users/Shared/.SelfServeManifest,
+	// which managedsoftwareupdate copies here at the start of each run.
+	path := filepath.Join(managedInstallDir(fs), "manifests", selfServeManifest)
+	manifest, err := loadMunkiManifest(fs, path)
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, nil
+	}
+
+	return buildSelfServiceOutput(manifest), nil
+}
+
+func buildSelfServiceOutput(manifest *munkiManifest) []map[string]string {
+	var results []map[string]string
+	for _, name := range manifest.ManagedInstalls {
+		results = append(results, map[string]string{"name": name, "action": "install"})
+	}
+	for _, name := range manifest.ManagedUninstalls {
+		results = append(results, map[string]string{"name": name, "action": "uninstall"})
+	}
+	return results
+}
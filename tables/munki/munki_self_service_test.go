@@ -0,0 +1,39 @@
+package munki
+
+import (
+	"context"
+	_ "embed"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed test_SelfServeManifest.plist
+var testSelfServeManifest []byte
+
+func TestMunkiSelfServiceGenerate(t *testing.T) {
+	reportPath := testReportPath(t)
+	manifestDir := filepath.Join(filepath.Dir(reportPath), "manifests")
+	require.NoError(t, os.MkdirAll(manifestDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(manifestDir, selfServeManifest), testSelfServeManifest, 0600))
+
+	rows, err := MunkiSelfServiceGenerate(context.Background(), table.QueryContext{})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{"name": "Slack", "action": "install"},
+		{"name": "Zoom", "action": "install"},
+		{"name": "Firefox", "action": "uninstall"},
+	}, rows)
+}
+
+func TestMunkiSelfServiceGenerateMissing(t *testing.T) {
+	testReportPath(t)
+
+	rows, err := MunkiSelfServiceGenerate(context.Background(), table.QueryContext{})
+	assert.NoError(t, err)
+	assert.Empty(t, rows)
+}
@@ -20,8 +20,38 @@ var testManagedInstallReportMunki7 []byte
 //go:embed test_ManagedInstallReport_with_pending.plist
 var testManagedInstallReportWithPending []byte
 
+// testReportPath points ManagedInstallDir and LogFile at a temporary directory
+// through a preference file and returns the path of the report within it.
+func testReportPath(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	prefsPath := filepath.Join(t.TempDir(), "ManagedInstalls.plist")
+	prefs := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>ManagedInstallDir</key>
+	<string>` + dir + `</string>
+	<key>LogFile</key>
+	<string>` + filepath.Join(dir, "Logs", "ManagedSoftwareUpdate.log") + `</string>
+</dict>
+</plist>`
+	if err := os.WriteFile(prefsPath, []byte(prefs), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	original := preferenceSources
+	preferenceSources = []preferenceSource{
+		{Path: filepath.Join(dir, "missing.plist"), Managed: true},
+		{Path: prefsPath, Managed: false},
+	}
+	t.Cleanup(func() { preferenceSources = original })
+
+	return filepath.Join(dir, "ManagedInstallReport.plist")
+}
+
 func TestMunkiInstallsGenerate(t *testing.T) {
-	reportPath = filepath.Join(t.TempDir(), "ManagedInstallReport.plist")
+	reportPath := testReportPath(t)
 	err := os.WriteFile(reportPath, testManagedInstallReport, 0600)
 	if err != nil {
 		t.Fatal(err)
@@ -54,7 +84,7 @@ func TestMunkiInstallsGenerate(t *testing.T) {
 }
 
 func TestMunkiInstallsGenerateMunki7(t *testing.T) {
-	reportPath = filepath.Join(t.TempDir(), "ManagedInstallReport.plist")
+	reportPath := testReportPath(t)
 	err := os.WriteFile(reportPath, testManagedInstallReportMunki7, 0600)
 	if err != nil {
 		t.Fatal(err)
@@ -78,7 +108,7 @@ func TestMunkiInstallsGenerateMunki7(t *testing.T) {
 }
 
 func TestMunkiInfoGenerateMunki7(t *testing.T) {
-	reportPath = filepath.Join(t.TempDir(), "ManagedInstallReport.plist")
+	reportPath := testReportPath(t)
 	err := os.WriteFile(reportPath, testManagedInstallReportMunki7, 0600)
 	if err != nil {
 		t.Fatal(err)
@@ -105,7 +135,7 @@ func TestMunkiInfoGenerateMunki7(t *testing.T) {
 }
 
 func TestMunkiInstallsGenerateWithPendingVersions(t *testing.T) {
-	reportPath = filepath.Join(t.TempDir(), "ManagedInstallReport.plist")
+	reportPath := testReportPath(t)
 	err := os.WriteFile(reportPath, testManagedInstallReportWithPending, 0600)
 	if err != nil {
 		t.Fatal(err)
@@ -0,0 +1,126 @@
+package puppet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/pkg/errors"
+)
+
+// agentSettings are the puppet.conf settings reported by puppet_agent_status.
+var agentSettings = []string{"server", "environment", "runinterval", "certname"}
+
+// processAlive is defined as a global variable to ease testing.
+var processAlive = func(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// on Windows FindProcess fails for processes that do not exist
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	err = process.Signal(syscall.Signal(0))
+	// EPERM means the process exists but belongs to another user
+	return err == nil || errors.Is(err, os.ErrPermission)
+}
+
+type disabledLock struct {
+	DisabledMessage string `json:"disabled_message"`
+}
+
+func PuppetAgentStatusColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("disabled"),
+		table.TextColumn("disabled_message"),
+		table.BigIntColumn("disabled_since"),
+		table.TextColumn("run_in_progress"),
+		table.IntegerColumn("run_pid"),
+		table.TextColumn("run_pid_alive"),
+		table.BigIntColumn("run_started"),
+		table.TextColumn("server"),
+		table.TextColumn("environment"),
+		table.TextColumn("runinterval"),
+		table.TextColumn("certname"),
+		table.TextColumn("settings_source"),
+		table.TextColumn("config_path"),
+	}
+}
+
+func PuppetAgentStatusGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	row, err := agentStatusRow(stateDir(), puppetConfPath())
+	if err != nil {
+		return nil, err
+	}
+	return []map[string]string{row}, nil
+}
+
+func agentStatusRow(stateDir, confPath string) (map[string]string, error) {
+	row := map[string]string{
+		"disabled":         "false",
+		"disabled_message": "",
+		"disabled_since":   "",
+		"run_in_progress":  "false",
+		"run_pid":          "",
+		"run_pid_alive":    "",
+		"run_started":      "",
+		"config_path":      confPath,
+	}
+
+	// puppet agent --disable "reason" writes the reason as json
+	disabledPath := filepath.Join(stateDir, "agent_disabled.lock")
+	if info, err := os.Stat(disabledPath); err == nil {
+		row["disabled"] = "true"
+		row["disabled_since"] = strconv.FormatInt(info.ModTime().Unix(), 10)
+		data, err := os.ReadFile(disabledPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "read agent_disabled.lock")
+		}
+		var lock disabledLock
+		if err := json.Unmarshal(data, &lock); err == nil {
+			row["disabled_message"] = lock.DisabledMessage
+		}
+	}
+
+	// the run lock holds the pid of the agent applying a catalog, and can be
+	// left behind by an agent that was killed
+	runPath := filepath.Join(stateDir, "agent_catalog_run.lock")
+	if info, err := os.Stat(runPath); err == nil {
+		row["run_in_progress"] = "true"
+		row["run_started"] = strconv.FormatInt(info.ModTime().Unix(), 10)
+		data, err := os.ReadFile(runPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "read agent_catalog_run.lock")
+		}
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+			alive := processAlive(pid)
+			row["run_pid"] = strconv.Itoa(pid)
+			row["run_pid_alive"] = fmt.Sprintf("%v", alive)
+			row["run_in_progress"] = fmt.Sprintf("%v", alive)
+		}
+	}
+
+	conf, err := loadPuppetConf(confPath)
+	if err != nil {
+		return nil, err
+	}
+	var sources []string
+	for _, key := range agentSettings {
+		value, section := conf.agentSetting(key)
+		row[key] = value
+		if section != "" {
+			sources = append(sources, key+"="+section)
+		}
+	}
+	row["settings_source"] = strings.Join(sources, ";")
+
+	return row, nil
+}
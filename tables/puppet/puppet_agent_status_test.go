@@ -0,0 +1,80 @@
+package puppet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupAgentTree writes a statedir and puppet.conf fixture tree and returns
+// their paths.
+func setupAgentTree(t *testing.T, files map[string]string) (string, string) {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	}
+	return filepath.Join(root, "opt/puppetlabs/puppet/cache/state"), filepath.Join(root, "etc/puppetlabs/puppet/puppet.conf")
+}
+
+func setupProcessAlive(t *testing.T, alive map[int]bool) {
+	t.Helper()
+	original := processAlive
+	processAlive = func(pid int) bool { return alive[pid] }
+	t.Cleanup(func() { processAlive = original })
+}
+
+func TestAgentStatusRow(t *testing.T) {
+	setupProcessAlive(t, map[int]bool{12345: true})
+	stateDir, confPath := setupAgentTree(t, map[string]string{
+		"opt/puppetlabs/puppet/cache/state/agent_disabled.lock":    `{"disabled_message":"maintenance window"}`,
+		"opt/puppetlabs/puppet/cache/state/agent_catalog_run.lock": "12345\n",
+		"etc/puppetlabs/puppet/puppet.conf":                        string(testPuppetConf),
+	})
+
+	row, err := agentStatusRow(stateDir, confPath)
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, row["disabled_since"])
+	assert.NotEmpty(t, row["run_started"])
+	delete(row, "disabled_since")
+	delete(row, "run_started")
+	assert.Equal(t, map[string]string{
+		"disabled":         "true",
+		"disabled_message": "maintenance window",
+		"run_in_progress":  "true",
+		"run_pid":          "12345",
+		"run_pid_alive":    "true",
+		"server":           "puppet.example.com",
+		"environment":      "staging",
+		"runinterval":      "1h",
+		"certname":         "fallback.example.com",
+		"settings_source":  "server=main;environment=agent;runinterval=agent;certname=main",
+		"config_path":      confPath,
+	}, row)
+}
+
+func TestAgentStatusRowStaleRunLock(t *testing.T) {
+	setupProcessAlive(t, map[int]bool{})
+	stateDir, confPath := setupAgentTree(t, map[string]string{
+		"opt/puppetlabs/puppet/cache/state/agent_catalog_run.lock": "4242",
+	})
+
+	row, err := agentStatusRow(stateDir, confPath)
+	require.NoError(t, err)
+	assert.Equal(t, "false", row["disabled"])
+	assert.Equal(t, "false", row["run_in_progress"])
+	assert.Equal(t, "4242", row["run_pid"])
+	assert.Equal(t, "false", row["run_pid_alive"])
+	assert.Equal(t, "", row["server"])
+	assert.Equal(t, "", row["settings_source"])
+}
+
+func TestProcessAlive(t *testing.T) {
+	assert.True(t, processAlive(os.Getpid()))
+}
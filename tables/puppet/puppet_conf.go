@@ -0,0 +1,78 @@
+package puppet
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// puppetConf holds the settings of puppet.conf by section.
+type puppetConf map[string]map[string]string
+
+func puppetConfPath() string {
+	if runtime.GOOS == "windows" {
+		return "C:\\ProgramData\\PuppetLabs\\puppet\\etc\\puppet.conf"
+	}
+
+	return "/etc/puppetlabs/puppet/puppet.conf"
+}
+
+// loadPuppetConf parses an ini style puppet.conf. A missing file is treated as
+// empty, as Puppet then uses its defaults.
+func loadPuppetConf(path string) (puppetConf, error) {
+	conf := puppetConf{}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return conf, nil
+		}
+		return nil, errors.Wrap(err, "open puppet.conf")
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("close puppet.conf: %v", err)
+		}
+	}()
+
+	// settings before any section header belong to main
+	section := "main"
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		if conf[section] == nil {
+			conf[section] = map[string]string{}
+		}
+		conf[section][strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read puppet.conf")
+	}
+
+	return conf, nil
+}
+
+// agentSetting returns a setting as the agent sees it: [agent] takes precedence
+// over [main]. The section the value came from is returned with it.
+func (c puppetConf) agentSetting(key string) (string, string) {
+	for _, section := range []string{"agent", "main"} {
+		if value, ok := c[section][key]; ok {
+			return value, section
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,140 @@
+package puppet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/pkg/errors"
+)
+
+const redactedValue = "[redacted]"
+
+// Event is a change Puppet made, or would have made in noop, to a property of
+// a resource.
+type Event struct {
+	Property         string      `yaml:"property"`
+	PreviousValue    interface{} `yaml:"previous_value"`
+	DesiredValue     interface{} `yaml:"desired_value"`
+	HistoricalValue  interface{} `yaml:"historical_value"`
+	Message          string      `yaml:"message"`
+	Name             string      `yaml:"name"`
+	Status           string      `yaml:"status"`
+	Time             string      `yaml:"time"`
+	Audited          string      `yaml:"audited"`
+	CorrectiveChange string      `yaml:"corrective_change"`
+	Redacted         string      `yaml:"redacted"`
+}
+
+func PuppetEventsColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("title"),
+		table.TextColumn("resource"),
+		table.TextColumn("resource_type"),
+		table.TextColumn("property"),
+		table.TextColumn("previous_value"),
+		table.TextColumn("desired_value"),
+		table.TextColumn("historical_value"),
+		table.TextColumn("status"),
+		table.TextColumn("message"),
+		table.TextColumn("name"),
+		table.TextColumn("time"),
+		table.TextColumn("audited"),
+		table.TextColumn("corrective_change"),
+		table.TextColumn("redacted"),
+	}
+}
+
+func PuppetEventsGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	runData, err := getPuppetYaml()
+	if err != nil {
+		return nil, err
+	}
+
+	return buildEventsOutput(runData, redactedResourceTypes())
+}
+
+// redactedResourceTypes returns the resource types listed in the comma
+// separated PUPPET_EVENTS_REDACT_TYPES env, whose values are never returned.
+func redactedResourceTypes() map[string]bool {
+	types := map[string]bool{}
+	for _, resourceType := range strings.Split(os.Getenv("PUPPET_EVENTS_REDACT_TYPES"), ",") {
+		if resourceType = strings.TrimSpace(resourceType); resourceType != "" {
+			types[strings.ToLower(resourceType)] = true
+		}
+	}
+	return types
+}
+
+func buildEventsOutput(runData *PuppetInfo, redactTypes map[string]bool) ([]map[string]string, error) {
+	var results []map[string]string
+
+	resources := make([]string, 0, len(runData.ResourceStatuses))
+	for resource := range runData.ResourceStatuses {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+
+	for _, resource := range resources {
+		item := runData.ResourceStatuses[resource]
+		for _, event := range item.Events {
+			// Puppet redacts Sensitive values itself and marks the event
+			redact := redactTypes[strings.ToLower(item.ResourceType)] || event.Redacted == "true"
+
+			message := event.Message
+			if redact && message != "" {
+				// the message quotes the values it describes
+				message = redactedValue
+			}
+			values := make([]string, 3)
+			for i, value := range []interface{}{event.PreviousValue, event.DesiredValue, event.HistoricalValue} {
+				str, err := eventValueString(value)
+				if err != nil {
+					return nil, err
+				}
+				if redact && str != "" {
+					str = redactedValue
+				}
+				values[i] = str
+			}
+
+			results = append(results, map[string]string{
+				"title":             item.Title,
+				"resource":          item.Resource,
+				"resource_type":     item.ResourceType,
+				"property":          event.Property,
+				"previous_value":    values[0],
+				"desired_value":     values[1],
+				"historical_value":  values[2],
+				"status":            event.Status,
+				"message":           message,
+				"name":              event.Name,
+				"time":              event.Time,
+				"audited":           event.Audited,
+				"corrective_change": event.CorrectiveChange,
+				"redacted":          fmt.Sprintf("%v", redact),
+			})
+		}
+	}
+
+	return results, nil
+}
+
+func eventValueString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case map[string]interface{}, []interface{}:
+		jsonStr, err := json.Marshal(v)
+		if err != nil {
+			return "", errors.Wrap(err, "marshal to json string")
+		}
+		return string(jsonStr), nil
+	default:
+		return fmt.Sprintf("%v", v), nil
+	}
+}
@@ -0,0 +1,96 @@
+package puppet
+
+import (
+	_ "embed"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed test_last_run_report.yaml
+var testLastRunReport []byte
+
+func loadTestReport(t *testing.T) *PuppetInfo {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "last_run_report.yaml")
+	require.NoError(t, os.WriteFile(path, testLastRunReport, 0600))
+
+	runData, err := loadPuppetYaml(path)
+	require.NoError(t, err)
+	return runData
+}
+
+func TestBuildEventsOutput(t *testing.T) {
+	rows, err := buildEventsOutput(loadTestReport(t), map[string]bool{})
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	assert.Equal(t, map[string]string{
+		"title":             "/etc/motd",
+		"resource":          "File[/etc/motd]",
+		"resource_type":     "File",
+		"property":          "content",
+		"previous_value":    "{md5}1f3870be274f6c49b3e31a0c6728957f",
+		"desired_value":     "{md5}8d777f385d3dfec8815d20f7496026dc",
+		"historical_value":  "",
+		"status":            "success",
+		"message":           "content changed '{md5}1f3870be274f6c49b3e31a0c6728957f' to '{md5}8d777f385d3dfec8815d20f7496026dc'",
+		"name":              "content_changed",
+		"time":              "2024-01-14T12:00:01.000000000+00:00",
+		"audited":           "false",
+		"corrective_change": "false",
+		"redacted":          "false",
+	}, rows[0])
+
+	assert.Equal(t, "deploy", rows[1]["title"])
+	assert.Equal(t, `["wheel"]`, rows[1]["previous_value"])
+	assert.Equal(t, `["wheel","docker"]`, rows[1]["desired_value"])
+	assert.Equal(t, "true", rows[1]["corrective_change"])
+
+	// values Puppet redacted itself are flagged
+	assert.Equal(t, "password", rows[2]["property"])
+	assert.Equal(t, "true", rows[2]["redacted"])
+}
+
+func TestBuildEventsOutputRedactTypes(t *testing.T) {
+	t.Setenv("PUPPET_EVENTS_REDACT_TYPES", " file, Exec")
+	redactTypes := redactedResourceTypes()
+	assert.Equal(t, map[string]bool{"file": true, "exec": true}, redactTypes)
+
+	rows, err := buildEventsOutput(loadTestReport(t), redactTypes)
+	require.NoError(t, err)
+	assert.Equal(t, redactedValue, rows[0]["previous_value"])
+	assert.Equal(t, redactedValue, rows[0]["desired_value"])
+	assert.Equal(t, "", rows[0]["historical_value"])
+	assert.Equal(t, redactedValue, rows[0]["message"])
+	assert.Equal(t, "true", rows[0]["redacted"])
+
+	// other types are untouched
+	assert.Equal(t, `["wheel"]`, rows[1]["previous_value"])
+}
+
+func TestBuildEventsOutputRedactShortValues(t *testing.T) {
+	runData := &PuppetInfo{ResourceStatuses: map[string]ResourceStatus{
+		"Exec[enable]": {
+			Title:        "enable",
+			Resource:     "Exec[enable]",
+			ResourceType: "Exec",
+			Events: []Event{{
+				Property:      "returns",
+				PreviousValue: 1,
+				DesiredValue:  true,
+				Message:       "executed successfully, returned 1 after 10 tries",
+			}},
+		},
+	}}
+
+	rows, err := buildEventsOutput(runData, map[string]bool{"exec": true})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, redactedValue, rows[0]["previous_value"])
+	assert.Equal(t, redactedValue, rows[0]["desired_value"])
+	assert.Equal(t, redactedValue, rows[0]["message"])
+}
@@ -4,17 +4,23 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/macadmins/osquery-extension/pkg/utils"
 	"github.com/osquery/osquery-go/plugin/table"
 	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
 )
 
 type puppetFacts struct {
-	Name   string
-	Values map[string]interface{}
+	Name   string                 `yaml:"name"`
+	Values map[string]interface{} `yaml:"values"`
 }
 
 var puppetPath = map[string]string{
@@ -23,59 +29,161 @@ var puppetPath = map[string]string{
 	"windows": "C:\\\\Program Files\\Puppet Labs\\Puppet\\bin\\puppet.bat",
 }
 
+var facterPath = map[string]string{
+	"linux":   "/opt/puppetlabs/bin/facter",
+	"darwin":  "/opt/puppetlabs/bin/facter",
+	"windows": "C:\\\\Program Files\\Puppet Labs\\Puppet\\bin\\facter.bat",
+}
+
 func PuppetFactsColumns() []table.ColumnDefinition {
 	return []table.ColumnDefinition{
 		table.TextColumn("node"),
 		table.TextColumn("fact"),
+		table.TextColumn("path"),
 		table.TextColumn("value"),
+		table.IntegerColumn("flatten"),
 	}
 }
 
 func PuppetFactsGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
-	var results []map[string]string
+	var queries []string
+	if constraintList, present := queryContext.Constraints["fact"]; present {
+		// 'fact' is in the where clause
+		for _, constraint := range constraintList.Constraints {
+			// =
+			if constraint.Operator == table.OperatorEquals {
+				queries = append(queries, constraint.Expression)
+			}
+		}
+	}
 
-	facts, err := getPuppetFacts()
+	flatten := false
+	if constraintList, present := queryContext.Constraints["flatten"]; present {
+		for _, constraint := range constraintList.Constraints {
+			if constraint.Operator == table.OperatorEquals {
+				flatten = constraint.Expression == "1"
+			}
+		}
+	}
+
+	facts, err := getPuppetFacts(utils.NewRunner().Runner, queries)
 	if err != nil {
 		return nil, err
 	}
 
-	for factName, factValue := range facts.Values {
-		var value string
+	return buildFactsOutput(facts, flatten)
+}
+
+func buildFactsOutput(facts *puppetFacts, flatten bool) ([]map[string]string, error) {
+	var results []map[string]string
+
+	factNames := make([]string, 0, len(facts.Values))
+	for factName := range facts.Values {
+		factNames = append(factNames, factName)
+	}
+	sort.Strings(factNames)
 
-		switch factValue.(type) {
-		case map[string]interface{}, []interface{}:
-			// serialize it as json string if it is a map or a slice
-			if jsonStr, err := json.Marshal(factValue); err != nil {
-				return nil, errors.Wrap(err, "marshal to json string")
-			} else {
-				value = string(jsonStr)
+	for _, factName := range factNames {
+		factValue := facts.Values[factName]
+		if !flatten {
+			value, err := factValueString(factValue)
+			if err != nil {
+				return nil, err
 			}
-		default:
-			// else serialize it as string
-			value = fmt.Sprintf("%v", factValue)
+			results = append(results, map[string]string{
+				"node":    facts.Name,
+				"fact":    factName,
+				"path":    factName,
+				"value":   value,
+				"flatten": "0",
+			})
+			continue
 		}
 
-		result := map[string]string{
-			"node":  facts.Name,
-			"fact":  factName,
-			"value": value,
+		err := flattenFact(factName, factValue, func(path, value string) {
+			results = append(results, map[string]string{
+				"node":    facts.Name,
+				"fact":    factName,
+				"path":    path,
+				"value":   value,
+				"flatten": "1",
+			})
+		})
+		if err != nil {
+			return nil, err
 		}
-		results = append(results, result)
 	}
 
 	return results, nil
 }
 
-func getPuppetFacts() (*puppetFacts, error) {
+// flattenFact calls emit with the dotted path of every leaf of a structured
+// fact. Array elements are addressed by index.
+func flattenFact(path string, factValue interface{}, emit func(path, value string)) error {
+	switch v := factValue.(type) {
+	case map[string]interface{}:
+		if len(v) > 0 {
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				if err := flattenFact(path+"."+key, v[key], emit); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			for i, item := range v {
+				if err := flattenFact(path+"."+strconv.Itoa(i), item, emit); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	value, err := factValueString(factValue)
+	if err != nil {
+		return err
+	}
+	emit(path, value)
+	return nil
+}
+
+func factValueString(factValue interface{}) (string, error) {
+	switch factValue.(type) {
+	case map[string]interface{}, []interface{}:
+		// serialize it as json string if it is a map or a slice
+		jsonStr, err := json.Marshal(factValue)
+		if err != nil {
+			return "", errors.Wrap(err, "marshal to json string")
+		}
+		return string(jsonStr), nil
+	default:
+		// else serialize it as string
+		return fmt.Sprintf("%v", factValue), nil
+	}
+}
+
+// getPuppetFacts resolves the queried facts, or all facts when there are no
+// queries. Without an agent, the facts cached by the last run are used.
+func getPuppetFacts(runner utils.CmdRunner, queries []string) (*puppetFacts, error) {
+	if len(queries) > 0 {
+		return getQueriedFacts(runner, queries)
+	}
+
 	// check if puppet command exists
 	execPath, err := getPuppetExecPath()
 	if err != nil {
-		return nil, err
+		return loadCachedFacts(cachedFactsDir[runtime.GOOS], queries)
 	}
 
 	// execute command
-	cmd := exec.Command(execPath, "facts", "--render-as", "json")
-	out, err := cmd.Output()
+	out, err := runner.RunCmd(execPath, "facts", "--render-as", "json")
 	if err != nil {
 		return nil, errors.Wrap(err, "calling puppet facts to get puppet facts")
 	}
@@ -88,6 +196,147 @@ func getPuppetFacts() (*puppetFacts, error) {
 	return &facts, nil
 }
 
+// getQueriedFacts resolves queries with facter, as it only resolves what is
+// asked for, then with puppet facts show, then from the cached facts, moving
+// on whenever a command is missing or fails.
+func getQueriedFacts(runner utils.CmdRunner, queries []string) (*puppetFacts, error) {
+	// the node is named by the clientcert fact, resolved along with the queries
+	withNode := append(append([]string{}, queries...), nodeFact)
+
+	if execPath, err := getFacterExecPath(); err == nil {
+		args := append([]string{"--puppet", "--json"}, withNode...)
+		facts, err := runFactsQuery(runner, execPath, args, queries)
+		if err == nil {
+			return facts, nil
+		}
+		log.Printf("calling facter to get puppet facts: %v", err)
+	}
+
+	if execPath, err := getPuppetExecPath(); err == nil {
+		args := append(append([]string{"facts", "show"}, withNode...), "--render-as", "json")
+		facts, err := runFactsQuery(runner, execPath, args, queries)
+		if err == nil {
+			return facts, nil
+		}
+		log.Printf("calling puppet facts show to get puppet facts: %v", err)
+	}
+
+	return loadCachedFacts(cachedFactsDir[runtime.GOOS], queries)
+}
+
+func runFactsQuery(runner utils.CmdRunner, execPath string, args, queries []string) (*puppetFacts, error) {
+	out, err := runner.RunCmd(execPath, args...)
+	if err != nil {
+		return nil, err
+	}
+	return queriedFacts(out, queries)
+}
+
+// nodeFact is the fact holding the certname of the node.
+const nodeFact = "clientcert"
+
+// queriedFacts decodes facter output for queried facts, which is keyed by query.
+// The node is named by nodeFact, which is only kept if it was queried.
+func queriedFacts(out []byte, queries []string) (*puppetFacts, error) {
+	var values map[string]interface{}
+	if err := json.Unmarshal(out, &values); err != nil {
+		return nil, errors.Wrap(err, "unmarshal facts output")
+	}
+
+	facts := &puppetFacts{Values: values}
+	if node, ok := values[nodeFact].(string); ok {
+		facts.Name = node
+	}
+	queried := map[string]bool{}
+	for _, query := range queries {
+		queried[query] = true
+	}
+	for query, value := range values {
+		// facts that do not resolve are null
+		if value == nil || !queried[query] {
+			delete(values, query)
+		}
+	}
+	return facts, nil
+}
+
+// cachedFactsDir is the directory the agent caches its facts in, one file per
+// certname.
+var cachedFactsDir = map[string]string{
+	"linux":   "/opt/puppetlabs/puppet/cache/client_yaml/facts",
+	"darwin":  "/opt/puppetlabs/puppet/cache/client_yaml/facts",
+	"windows": "C:\\ProgramData\\PuppetLabs\\puppet\\cache\\client_yaml\\facts",
+}
+
+// loadCachedFacts reads the most recently cached facts in dir, keeping only
+// the queried facts if any.
+func loadCachedFacts(dir string, queries []string) (*puppetFacts, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil || len(paths) == 0 {
+		return nil, errors.New("puppet facts unavailable and no cached facts.")
+	}
+
+	var latest string
+	var latestInfo os.FileInfo
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if latestInfo == nil || info.ModTime().After(latestInfo.ModTime()) {
+			latest, latestInfo = path, info
+		}
+	}
+	if latestInfo == nil {
+		return nil, errors.New("puppet facts unavailable and no cached facts.")
+	}
+
+	data, err := os.ReadFile(latest)
+	if err != nil {
+		return nil, errors.Wrap(err, "read cached facts")
+	}
+	var facts puppetFacts
+	if err := yaml.Unmarshal(data, &facts); err != nil {
+		return nil, errors.Wrap(err, "unmarshal cached facts")
+	}
+
+	if len(queries) == 0 {
+		return &facts, nil
+	}
+	values := map[string]interface{}{}
+	for _, query := range queries {
+		if value, ok := lookupFact(facts.Values, query); ok {
+			values[query] = value
+		}
+	}
+	facts.Values = values
+	return &facts, nil
+}
+
+// lookupFact resolves a dotted fact query such as os.release.major.
+func lookupFact(values map[string]interface{}, query string) (interface{}, bool) {
+	var current interface{} = values
+	for _, segment := range strings.Split(query, ".") {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			next, ok := v[segment]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			current = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
 func getPuppetExecPath() (string, error) {
 	// if puppet command not in the path, try to use the predefined path
 	if execPath, ok := puppetPath[runtime.GOOS]; ok {
@@ -104,3 +353,18 @@ func getPuppetExecPath() (string, error) {
 	// puppet command not found
 	return "", errors.New("puppet command not found.")
 }
+
+func getFacterExecPath() (string, error) {
+	if execPath, ok := facterPath[runtime.GOOS]; ok {
+		if _, err := os.Stat(execPath); !os.IsNotExist(err) {
+			return execPath, nil
+		}
+	}
+
+	// if user specified FACTER_PATH env, try to use it
+	if execPath := os.Getenv("FACTER_PATH"); execPath != "" {
+		return execPath, nil
+	}
+
+	return "", errors.New("facter command not found.")
+}
@@ -0,0 +1,175 @@
+package puppet
+
+import (
+	_ "embed"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/macadmins/osquery-extension/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed test_facts.yaml
+var testFacts []byte
+
+// setupExecPaths points the puppet and facter paths at files that exist only
+// when requested, and returns those paths.
+func setupExecPaths(t *testing.T, puppet, facter bool) (string, string) {
+	t.Helper()
+	t.Setenv("PUPPET_PATH", "")
+	t.Setenv("FACTER_PATH", "")
+	dir := t.TempDir()
+	puppetExec := filepath.Join(dir, "puppet")
+	facterExec := filepath.Join(dir, "facter")
+	if puppet {
+		require.NoError(t, os.WriteFile(puppetExec, nil, 0700))
+	}
+	if facter {
+		require.NoError(t, os.WriteFile(facterExec, nil, 0700))
+	}
+
+	originalPuppet, originalFacter := puppetPath[runtime.GOOS], facterPath[runtime.GOOS]
+	puppetPath[runtime.GOOS], facterPath[runtime.GOOS] = puppetExec, facterExec
+	t.Cleanup(func() {
+		puppetPath[runtime.GOOS], facterPath[runtime.GOOS] = originalPuppet, originalFacter
+	})
+
+	return puppetExec, facterExec
+}
+
+func TestGetPuppetFactsAll(t *testing.T) {
+	puppetExec, _ := setupExecPaths(t, true, true)
+	runner := utils.MultiMockCmdRunner{
+		Commands: map[string]utils.MockCmdRunner{
+			puppetExec + " facts --render-as json": {
+				Output: `{"name": "node.example.com", "values": {"kernel": "Linux", "os": {"release": {"major": "9"}}}}`,
+			},
+		},
+	}
+
+	facts, err := getPuppetFacts(runner, nil)
+	require.NoError(t, err)
+	rows, err := buildFactsOutput(facts, false)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{"node": "node.example.com", "fact": "kernel", "path": "kernel", "value": "Linux", "flatten": "0"},
+		{"node": "node.example.com", "fact": "os", "path": "os", "value": `{"release":{"major":"9"}}`, "flatten": "0"},
+	}, rows)
+}
+
+func TestGetPuppetFactsQueryFacter(t *testing.T) {
+	_, facterExec := setupExecPaths(t, true, true)
+	runner := utils.MultiMockCmdRunner{
+		Commands: map[string]utils.MockCmdRunner{
+			facterExec + " --puppet --json os.release.major missing clientcert": {
+				Output: `{"os.release.major": "9", "missing": null, "clientcert": "node.example.com"}`,
+			},
+		},
+	}
+
+	facts, err := getPuppetFacts(runner, []string{"os.release.major", "missing"})
+	require.NoError(t, err)
+	assert.Equal(t, "node.example.com", facts.Name)
+	assert.Equal(t, map[string]interface{}{"os.release.major": "9"}, facts.Values)
+
+	// clientcert is kept when it is queried
+	runner.Commands[facterExec+" --puppet --json clientcert clientcert"] = utils.MockCmdRunner{
+		Output: `{"clientcert": "node.example.com"}`,
+	}
+	facts, err = getPuppetFacts(runner, []string{"clientcert"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"clientcert": "node.example.com"}, facts.Values)
+}
+
+func TestGetPuppetFactsQueryPuppet(t *testing.T) {
+	puppetExec, _ := setupExecPaths(t, true, false)
+	runner := utils.MultiMockCmdRunner{
+		Commands: map[string]utils.MockCmdRunner{
+			puppetExec + " facts show os.release clientcert --render-as json": {
+				Output: `{"os.release": {"full": "9.3", "major": "9"}, "clientcert": "node.example.com"}`,
+			},
+		},
+	}
+
+	facts, err := getPuppetFacts(runner, []string{"os.release"})
+	require.NoError(t, err)
+	rows, err := buildFactsOutput(facts, true)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{"node": "node.example.com", "fact": "os.release", "path": "os.release.full", "value": "9.3", "flatten": "1"},
+		{"node": "node.example.com", "fact": "os.release", "path": "os.release.major", "value": "9", "flatten": "1"},
+	}, rows)
+}
+
+func TestGetPuppetFactsQueryFallback(t *testing.T) {
+	puppetExec, facterExec := setupExecPaths(t, true, true)
+	cacheDir := t.TempDir()
+	originalCacheDir := cachedFactsDir[runtime.GOOS]
+	cachedFactsDir[runtime.GOOS] = cacheDir
+	t.Cleanup(func() { cachedFactsDir[runtime.GOOS] = originalCacheDir })
+
+	runner := utils.MultiMockCmdRunner{
+		Commands: map[string]utils.MockCmdRunner{
+			facterExec + " --puppet --json kernel clientcert": {
+				Err: errors.New("exit status 1"),
+			},
+			puppetExec + " facts show kernel clientcert --render-as json": {
+				Output: `{"kernel": "Linux", "clientcert": "node.example.com"}`,
+			},
+		},
+	}
+
+	// a failing facter falls back to puppet facts show
+	facts, err := getPuppetFacts(runner, []string{"kernel"})
+	require.NoError(t, err)
+	assert.Equal(t, "node.example.com", facts.Name)
+	assert.Equal(t, map[string]interface{}{"kernel": "Linux"}, facts.Values)
+
+	// then to the cached facts
+	runner.Commands[puppetExec+" facts show kernel clientcert --render-as json"] = utils.MockCmdRunner{
+		Err: errors.New("exit status 1"),
+	}
+	_, err = getPuppetFacts(runner, []string{"kernel"})
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(cacheDir, "node.example.com.yaml"), testFacts, 0600))
+	facts, err = getPuppetFacts(runner, []string{"kernel"})
+	require.NoError(t, err)
+	assert.Equal(t, "node.example.com", facts.Name)
+	assert.Equal(t, map[string]interface{}{"kernel": "Linux"}, facts.Values)
+}
+
+func TestLoadCachedFacts(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "node.example.com.yaml"), testFacts, 0600))
+
+	facts, err := loadCachedFacts(dir, nil)
+	require.NoError(t, err)
+	rows, err := buildFactsOutput(facts, true)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{"node": "node.example.com", "fact": "clientcert", "path": "clientcert", "value": "node.example.com", "flatten": "1"},
+		{"node": "node.example.com", "fact": "kernel", "path": "kernel", "value": "Linux", "flatten": "1"},
+		{"node": "node.example.com", "fact": "mountpoints", "path": "mountpoints", "value": "{}", "flatten": "1"},
+		{"node": "node.example.com", "fact": "os", "path": "os.family", "value": "RedHat", "flatten": "1"},
+		{"node": "node.example.com", "fact": "os", "path": "os.release.full", "value": "9.3", "flatten": "1"},
+		{"node": "node.example.com", "fact": "os", "path": "os.release.major", "value": "9", "flatten": "1"},
+		{"node": "node.example.com", "fact": "processors", "path": "processors.count", "value": "2", "flatten": "1"},
+		{"node": "node.example.com", "fact": "processors", "path": "processors.models.0", "value": "Intel Xeon", "flatten": "1"},
+		{"node": "node.example.com", "fact": "processors", "path": "processors.models.1", "value": "Intel Xeon", "flatten": "1"},
+	}, rows)
+
+	facts, err = loadCachedFacts(dir, []string{"os.release.major", "processors.models.1", "os.missing"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"os.release.major":    "9",
+		"processors.models.1": "Intel Xeon",
+	}, facts.Values)
+
+	_, err = loadCachedFacts(t.TempDir(), nil)
+	assert.Error(t, err)
+}
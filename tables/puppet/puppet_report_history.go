@@ -0,0 +1,190 @@
+package puppet
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/macadmins/osquery-extension/pkg/utils"
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// The store report processor names reports after the UTC minute they were
+// saved, at the end of the run.
+const reportFileLayout = "200601021504"
+
+// reportBodyKeys are the top level keys of a report that hold the bulk of it
+// and are skipped when reading the header.
+var reportBodyKeys = map[string]bool{
+	"logs":              true,
+	"metrics":           true,
+	"resource_statuses": true,
+}
+
+type ReportHeader struct {
+	Host                 string `yaml:"host"`
+	Time                 string `yaml:"time"`
+	ConfigurationVersion string `yaml:"configuration_version"`
+	TransactionUUID      string `yaml:"transaction_uuid"`
+	PuppetVersion        string `yaml:"puppet_version"`
+	Status               string `yaml:"status"`
+	Noop                 string `yaml:"noop"`
+	Environment          string `yaml:"environment"`
+	CorrectiveChange     string `yaml:"corrective_change"`
+	CachedCatalogStatus  string `yaml:"cached_catalog_status"`
+}
+
+func PuppetReportHistoryColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("time"),
+		table.TextColumn("host"),
+		table.TextColumn("status"),
+		table.TextColumn("transaction_uuid"),
+		table.TextColumn("configuration_version"),
+		table.TextColumn("environment"),
+		table.TextColumn("corrective_change"),
+		table.TextColumn("noop"),
+		table.TextColumn("cached_catalog_status"),
+		table.TextColumn("puppet_version"),
+		table.TextColumn("path"),
+	}
+}
+
+func PuppetReportHistoryGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	since, err := utils.TimeLowerBound(queryContext, "time")
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := reportPaths(reportDir(utils.NewRunner().Runner, puppetConfPath()), since)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []map[string]string
+	for _, path := range paths {
+		header, err := loadReportHeader(path)
+		if err != nil {
+			log.Printf("read Puppet report %s: %v", path, err)
+			continue
+		}
+		results = append(results, map[string]string{
+			"time":                  header.Time,
+			"host":                  header.Host,
+			"status":                header.Status,
+			"transaction_uuid":      header.TransactionUUID,
+			"configuration_version": header.ConfigurationVersion,
+			"environment":           header.Environment,
+			"corrective_change":     header.CorrectiveChange,
+			"noop":                  header.Noop,
+			"cached_catalog_status": header.CachedCatalogStatus,
+			"puppet_version":        header.PuppetVersion,
+			"path":                  path,
+		})
+	}
+
+	return results, nil
+}
+
+// reportDir asks the agent for its reportdir, falling back to puppet.conf and
+// then to the default.
+func reportDir(runner utils.CmdRunner, confPath string) string {
+	if execPath, err := getPuppetExecPath(); err == nil {
+		out, err := runner.RunCmd(execPath, "config", "print", "reportdir", "--section", "agent")
+		if dir := strings.TrimSpace(string(out)); err == nil && dir != "" {
+			return dir
+		}
+	}
+
+	conf, err := loadPuppetConf(confPath)
+	if err != nil {
+		log.Print(err)
+	}
+	if dir, _ := conf.agentSetting("reportdir"); dir != "" {
+		return dir
+	}
+	if dir, _ := conf.agentSetting("vardir"); dir != "" {
+		return filepath.Join(dir, "reports")
+	}
+
+	if runtime.GOOS == "windows" {
+		return "C:\\ProgramData\\PuppetLabs\\puppet\\cache\\reports"
+	}
+	return "/opt/puppetlabs/puppet/cache/reports"
+}
+
+// reportPaths returns the stored reports of every node in dir, oldest first.
+// A report is saved after its run started, so reports saved before since
+// cannot match and are skipped without being read.
+func reportPaths(dir string, since time.Time) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "*.yaml"))
+	if err != nil {
+		return nil, errors.Wrap(err, "list Puppet reports")
+	}
+
+	var kept []string
+	for _, path := range paths {
+		if !since.IsZero() {
+			saved, err := time.Parse(reportFileLayout, strings.TrimSuffix(filepath.Base(path), ".yaml"))
+			// the name is truncated to the minute
+			if err == nil && saved.Add(time.Minute).Before(since) {
+				continue
+			}
+		}
+		kept = append(kept, path)
+	}
+	sort.Slice(kept, func(i, j int) bool { return filepath.Base(kept[i]) < filepath.Base(kept[j]) })
+
+	return kept, nil
+}
+
+// loadReportHeader decodes the top level fields of a report, skipping the
+// logs, metrics and resource statuses which can be tens of megabytes.
+func loadReportHeader(path string) (*ReportHeader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("close Puppet report: %v", err)
+		}
+	}()
+
+	var header strings.Builder
+	skipping := false
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			topLevel := line[0] != ' ' && line[0] != '-' && line[0] != '#' && line[0] != '\n' && line[0] != '\r'
+			if topLevel {
+				key, _, _ := strings.Cut(line, ":")
+				skipping = reportBodyKeys[key]
+			}
+			if !skipping {
+				// lines end in \r\n in reports written on Windows
+				header.WriteString(strings.TrimRight(strings.TrimSuffix(line, "\n"), "\r") + "\n")
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	// the document is tagged !ruby/object:Puppet::Transaction::Report, which
+	// decodes as a plain mapping
+	var reportHeader ReportHeader
+	if err := yaml.Unmarshal([]byte(header.String()), &reportHeader); err != nil {
+		return nil, errors.Wrap(err, "unmarshal Puppet report")
+	}
+	return &reportHeader, nil
+}
@@ -0,0 +1,93 @@
+package puppet
+
+import (
+	_ "embed"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/macadmins/osquery-extension/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed test_puppet.conf
+var testPuppetConf []byte
+
+const testOlderReport = `--- !ruby/object:Puppet::Transaction::Report
+host: node.example.com
+time: '2024-01-13T10:00:00.000000000+00:00'
+transaction_uuid: 0c5a6f0e-9a51-4bb0-8f0f-2d4d9b0f6a11
+logs:
+- !ruby/object:Puppet::Util::Log
+  level: :err
+  message: 'Could not retrieve catalog'
+status: failed
+environment: production
+resource_statuses: {}
+corrective_change: false
+`
+
+func setupReportDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	nodeDir := filepath.Join(dir, "node.example.com")
+	require.NoError(t, os.MkdirAll(nodeDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(nodeDir, "202401141200.yaml"), testLastRunReport, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(nodeDir, "202401131001.yaml"), []byte(testOlderReport), 0600))
+	return dir
+}
+
+func TestReportPaths(t *testing.T) {
+	dir := setupReportDir(t)
+
+	paths, err := reportPaths(dir, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "node.example.com", "202401131001.yaml"),
+		filepath.Join(dir, "node.example.com", "202401141200.yaml"),
+	}, paths)
+
+	// reports saved before the bound are not read
+	paths, err = reportPaths(dir, time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "node.example.com", "202401141200.yaml")}, paths)
+}
+
+func TestLoadReportHeader(t *testing.T) {
+	dir := setupReportDir(t)
+
+	header, err := loadReportHeader(filepath.Join(dir, "node.example.com", "202401131001.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, &ReportHeader{
+		Host:             "node.example.com",
+		Time:             "2024-01-13T10:00:00.000000000+00:00",
+		TransactionUUID:  "0c5a6f0e-9a51-4bb0-8f0f-2d4d9b0f6a11",
+		Status:           "failed",
+		Environment:      "production",
+		CorrectiveChange: "false",
+	}, header)
+
+	header, err = loadReportHeader(filepath.Join(dir, "node.example.com", "202401141200.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "changed", header.Status)
+	assert.Equal(t, "5b1e3c1e-3f1a-4d8e-9c3b-7f3f6c2a1d10", header.TransactionUUID)
+	assert.Equal(t, "not_used", header.CachedCatalogStatus)
+}
+
+func TestReportDir(t *testing.T) {
+	puppetExec, _ := setupExecPaths(t, true, false)
+	runner := utils.MultiMockCmdRunner{
+		Commands: map[string]utils.MockCmdRunner{
+			puppetExec + " config print reportdir --section agent": {Output: "/opt/puppetlabs/puppet/cache/reports\n"},
+		},
+	}
+	assert.Equal(t, "/opt/puppetlabs/puppet/cache/reports", reportDir(runner, ""))
+
+	// without the agent, puppet.conf is used
+	setupExecPaths(t, false, false)
+	confPath := filepath.Join(t.TempDir(), "puppet.conf")
+	require.NoError(t, os.WriteFile(confPath, testPuppetConf, 0600))
+	assert.Equal(t, "/var/lib/puppet/agent_reports", reportDir(runner, confPath))
+}
@@ -0,0 +1,142 @@
+package puppet
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// RunSummary is the content of last_run_summary.yaml, which the agent writes
+// after every run alongside the much larger last run report.
+type RunSummary struct {
+	Version struct {
+		Config string `yaml:"config"`
+		Puppet string `yaml:"puppet"`
+	} `yaml:"version"`
+	Resources struct {
+		Total            int64 `yaml:"total"`
+		Changed          int64 `yaml:"changed"`
+		CorrectiveChange int64 `yaml:"corrective_change"`
+		Failed           int64 `yaml:"failed"`
+		FailedToRestart  int64 `yaml:"failed_to_restart"`
+		OutOfSync        int64 `yaml:"out_of_sync"`
+		Restarted        int64 `yaml:"restarted"`
+		Scheduled        int64 `yaml:"scheduled"`
+		Skipped          int64 `yaml:"skipped"`
+	} `yaml:"resources"`
+	Changes struct {
+		Total int64 `yaml:"total"`
+	} `yaml:"changes"`
+	Events struct {
+		Total   int64 `yaml:"total"`
+		Success int64 `yaml:"success"`
+		Failure int64 `yaml:"failure"`
+	} `yaml:"events"`
+	// Time holds the seconds spent per resource type and run phase, the
+	// total, and the unix time of the run as last_run.
+	Time map[string]float64 `yaml:"time"`
+}
+
+func PuppetRunSummaryColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("puppet_version"),
+		table.TextColumn("config_version"),
+		table.IntegerColumn("resources_total"),
+		table.IntegerColumn("resources_changed"),
+		table.IntegerColumn("resources_corrective_change"),
+		table.IntegerColumn("resources_failed"),
+		table.IntegerColumn("resources_failed_to_restart"),
+		table.IntegerColumn("resources_out_of_sync"),
+		table.IntegerColumn("resources_restarted"),
+		table.IntegerColumn("resources_scheduled"),
+		table.IntegerColumn("resources_skipped"),
+		table.IntegerColumn("changes_total"),
+		table.IntegerColumn("events_total"),
+		table.IntegerColumn("events_success"),
+		table.IntegerColumn("events_failure"),
+		table.DoubleColumn("time_total"),
+		table.TextColumn("timing"),
+		table.BigIntColumn("last_run"),
+		table.BigIntColumn("seconds_since_last_run"),
+	}
+}
+
+func PuppetRunSummaryGenerate(ctx context.Context, queryContext table.QueryContext) ([]map[string]string, error) {
+	summary, err := loadRunSummary(filepath.Join(stateDir(), "last_run_summary.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := runSummaryRow(summary, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return []map[string]string{row}, nil
+}
+
+func loadRunSummary(path string) (*RunSummary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read Puppet run summary")
+	}
+
+	var summary RunSummary
+	if err := yaml.Unmarshal(data, &summary); err != nil {
+		return nil, errors.Wrap(err, "unmarshal Puppet run summary")
+	}
+	return &summary, nil
+}
+
+func runSummaryRow(summary *RunSummary, now time.Time) (map[string]string, error) {
+	// last_run and total are not timings of a resource type or phase
+	timing := map[string]float64{}
+	for key, value := range summary.Time {
+		if key != "last_run" && key != "total" {
+			timing[key] = value
+		}
+	}
+	timingJSON, err := json.Marshal(timing)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal to json string")
+	}
+
+	lastRun, sinceLastRun := "", ""
+	if value, ok := summary.Time["last_run"]; ok {
+		lastRun = strconv.FormatInt(int64(value), 10)
+		sinceLastRun = strconv.FormatInt(int64(now.Sub(time.Unix(int64(value), 0)).Seconds()), 10)
+	}
+
+	timeTotal := ""
+	if value, ok := summary.Time["total"]; ok {
+		timeTotal = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	return map[string]string{
+		"puppet_version":              summary.Version.Puppet,
+		"config_version":              summary.Version.Config,
+		"resources_total":             strconv.FormatInt(summary.Resources.Total, 10),
+		"resources_changed":           strconv.FormatInt(summary.Resources.Changed, 10),
+		"resources_corrective_change": strconv.FormatInt(summary.Resources.CorrectiveChange, 10),
+		"resources_failed":            strconv.FormatInt(summary.Resources.Failed, 10),
+		"resources_failed_to_restart": strconv.FormatInt(summary.Resources.FailedToRestart, 10),
+		"resources_out_of_sync":       strconv.FormatInt(summary.Resources.OutOfSync, 10),
+		"resources_restarted":         strconv.FormatInt(summary.Resources.Restarted, 10),
+		"resources_scheduled":         strconv.FormatInt(summary.Resources.Scheduled, 10),
+		"resources_skipped":           strconv.FormatInt(summary.Resources.Skipped, 10),
+		"changes_total":               strconv.FormatInt(summary.Changes.Total, 10),
+		"events_total":                strconv.FormatInt(summary.Events.Total, 10),
+		"events_success":              strconv.FormatInt(summary.Events.Success, 10),
+		"events_failure":              strconv.FormatInt(summary.Events.Failure, 10),
+		"time_total":                  timeTotal,
+		"timing":                      string(timingJSON),
+		"last_run":                    lastRun,
+		"seconds_since_last_run":      sinceLastRun,
+	}, nil
+}
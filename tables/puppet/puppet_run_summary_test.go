@@ -0,0 +1,52 @@
+package puppet
+
+import (
+	_ "embed"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed test_last_run_summary.yaml
+var testLastRunSummary []byte
+
+func TestRunSummaryRow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "last_run_summary.yaml")
+	require.NoError(t, os.WriteFile(path, testLastRunSummary, 0600))
+
+	summary, err := loadRunSummary(path)
+	require.NoError(t, err)
+
+	row, err := runSummaryRow(summary, time.Unix(1705237200, 0))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"puppet_version":              "7.28.0",
+		"config_version":              "1705233590",
+		"resources_total":             "215",
+		"resources_changed":           "1",
+		"resources_corrective_change": "1",
+		"resources_failed":            "0",
+		"resources_failed_to_restart": "0",
+		"resources_out_of_sync":       "1",
+		"resources_restarted":         "0",
+		"resources_scheduled":         "0",
+		"resources_skipped":           "0",
+		"changes_total":               "1",
+		"events_total":                "1",
+		"events_success":              "1",
+		"events_failure":              "0",
+		"time_total":                  "6.18",
+		"timing":                      `{"catalog_application":2.54,"config_retrieval":1.21,"exec":0.5,"fact_generation":1.1,"file":0.83,"filebucket":0.0002,"package":1}`,
+		"last_run":                    "1705233600",
+		"seconds_since_last_run":      "3600",
+	}, row)
+}
+
+func TestLoadRunSummaryMissing(t *testing.T) {
+	_, err := loadRunSummary(filepath.Join(t.TempDir(), "last_run_summary.yaml"))
+	assert.Error(t, err)
+}
@@ -7,19 +7,20 @@ import (
 )
 
 type ResourceStatus struct {
-	Title            string `yaml:"title"`
-	File             string `yaml:"file"`
-	Line             string `yaml:"line"`
-	Resource         string `yaml:"resource"`
-	ResourceType     string `yaml:"resource_type"`
-	EvaulationTime   string `yaml:"evaluation_time"`
-	Failed           string `yaml:"failed"`
-	Changed          string `yaml:"changed"`
-	OutOfSync        string `yaml:"out_of_sync"`
-	Skipped          string `yaml:"skipped"`
-	ChangeCount      string `yaml:"change_count"`
-	OutOfSyncCount   string `yaml:"out_of_sync_count"`
-	CorrectiveChange string `yaml:"corrective_change"`
+	Title            string  `yaml:"title"`
+	File             string  `yaml:"file"`
+	Line             string  `yaml:"line"`
+	Resource         string  `yaml:"resource"`
+	ResourceType     string  `yaml:"resource_type"`
+	EvaulationTime   string  `yaml:"evaluation_time"`
+	Failed           string  `yaml:"failed"`
+	Changed          string  `yaml:"changed"`
+	OutOfSync        string  `yaml:"out_of_sync"`
+	Skipped          string  `yaml:"skipped"`
+	ChangeCount      string  `yaml:"change_count"`
+	OutOfSyncCount   string  `yaml:"out_of_sync_count"`
+	CorrectiveChange string  `yaml:"corrective_change"`
+	Events           []Event `yaml:"events"`
 }
 
 // PuppetStateColumns returns the type hinted columns for the logged in user.
@@ -4,24 +4,35 @@ import (
 	"bytes"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
-func yamlPath() string {
+// stateDir returns the agent's statedir, which holds the last run report and
+// summary.
+func stateDir() string {
 	if runtime.GOOS == "windows" {
-		return "C:\\ProgramData\\PuppetLabs\\puppet\\cache\\state\\last_run_report.yaml"
+		return "C:\\ProgramData\\PuppetLabs\\puppet\\cache\\state"
 	}
 
-	return "/opt/puppetlabs/puppet/cache/state/last_run_report.yaml"
+	return "/opt/puppetlabs/puppet/cache/state"
+}
+
+func yamlPath() string {
+	return filepath.Join(stateDir(), "last_run_report.yaml")
 }
 
 func getPuppetYaml() (*PuppetInfo, error) {
+	return loadPuppetYaml(yamlPath())
+}
+
+func loadPuppetYaml(path string) (*PuppetInfo, error) {
 	var yamlData PuppetInfo
 
-	yamlFile, err := os.Open(yamlPath())
+	yamlFile, err := os.Open(path)
 	if err != nil {
 		log.Print(err)
 		return &yamlData, err
@@ -1,17 +1,12 @@
 package sofa
 
 import (
-	"compress/gzip"
-	"encoding/json"
 	"errors"
-	"io"
-	"log"
 	"net/http"
-	"os"
-	"path"
 	"strings"
 	"time"
 
+	"github.com/macadmins/osquery-extension/pkg/feed"
 	"github.com/macadmins/osquery-extension/pkg/utils"
 )
 
@@ -20,13 +15,13 @@ const SofaV1URL = "https://sofafeed.macadmins.io/v1/macos_data_feed.json"
 type SofaClient struct {
 	endpoint   string
 	httpClient *http.Client
-	localEtag  string
-	remoteEtag string
 	cacheFile  string
 	cacheDir   string
 	etagFile   string
+	cacheName  string
 	userAgent  string
 	fs         utils.FileSystem
+	fetcher    *feed.Fetcher
 }
 
 type SofaTime time.Time
@@ -64,6 +59,14 @@ func WithLocalCache(cacheFile, etagFile string) Option {
 	}
 }
 
+// WithCacheName sets the name of the cache directory the feed is stored in
+// below the cache directory, so several feeds can share one cache directory.
+func WithCacheName(name string) Option {
+	return func(s *SofaClient) {
+		s.cacheName = name
+	}
+}
+
 func WithUserAgent(userAgent string) Option {
 	return func(s *SofaClient) {
 		s.userAgent = userAgent
@@ -101,7 +104,8 @@ func NewSofaClient(opts ...Option) (*SofaClient, error) {
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-		cacheDir: "/private/tmp/sofa",
+		cacheDir:  "/private/tmp/sofa",
+		cacheName: "macos_data_feed",
 	}
 
 	for _, opt := range opts {
@@ -116,200 +120,40 @@ func NewSofaClient(opts ...Option) (*SofaClient, error) {
 		s.fs = utils.OSFileSystem{}
 	}
 
-	err := s.createCacheDir()
-	if err != nil {
-		return nil, err
-	}
-
-	s.setCachePaths()
-
-	return s, nil
-}
-
-func (s *SofaClient) setCachePaths() {
-	if s.etagFile == "" {
-		s.etagFile = s.etagPath("macos_data_feed.json.json")
-	}
-
-	if s.cacheFile == "" {
-		s.cacheFile = s.cachePath("macos_data_feed_etag.txt")
-	}
-}
-
-func (s *SofaClient) cachePath(fileName string) string {
-	return path.Join(s.cacheDir, fileName)
-}
-
-func (s *SofaClient) etagPath(fileName string) string {
-	return path.Join(s.cacheDir, fileName)
-}
-
-func (s *SofaClient) createCacheDir() error {
-
-	err := os.MkdirAll(s.cacheDir, 0755)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (s *SofaClient) cacheValid() (bool, error) {
-	if !utils.FileExists(s.fs, s.cacheFile) {
-		return false, nil
-	}
-
-	remoteEtag, err := s.getEtag()
-	if err != nil {
-		return false, err
-	}
-
-	localEtag, err := s.loadCachedEtag()
-	if err != nil {
-		return false, err
-	}
-
-	s.localEtag = localEtag
-	s.remoteEtag = remoteEtag
-
-	err = s.saveEtag()
-	if err != nil {
-		return false, err
-	}
-
-	if s.localEtag == s.remoteEtag {
-		return true, nil
-	}
-
-	return false, nil
-}
-
-func (s *SofaClient) loadCachedData() (Root, error) {
-
-	jsonData, err := os.ReadFile(s.cacheFile)
-	if err != nil {
-		return Root{}, err
-	}
-
-	var root Root
-	if err := json.Unmarshal(jsonData, &root); err != nil {
-		return Root{}, err
-	}
-
-	return root, nil
-
-}
-
-func (s *SofaClient) downloadData() error {
-	return s.downloadFile(s.endpoint, s.cacheFile)
-}
-
-func (s *SofaClient) loadCachedEtag() (string, error) {
-	if !utils.FileExists(s.fs, s.etagFile) {
-		return "", nil
-	}
-
-	etagData, err := os.ReadFile(s.etagFile)
-	if err != nil {
-		return "", err
+	feedOpts := []feed.Option{
+		feed.WithName(s.cacheName),
+		feed.WithCacheDir(s.cacheDir),
+		feed.WithUserAgent(s.userAgent),
+		feed.WithHTTPClient(s.httpClient),
+		feed.WithFileSystem(s.fs),
 	}
-
-	return string(etagData), nil
-}
-
-func (s *SofaClient) getEtag() (string, error) {
-	req, err := http.NewRequest("HEAD", s.endpoint, http.NoBody)
-	if err != nil {
-		return "", err
+	if s.cacheFile != "" && s.etagFile != "" {
+		feedOpts = append(feedOpts, feed.WithCacheFiles(s.cacheFile, s.etagFile))
 	}
 
-	req.Header.Set("User-Agent", s.userAgent)
-	resp, err := s.httpClient.Do(req)
+	fetcher, err := feed.New(s.endpoint, feedOpts...)
 	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close() // nolint: errcheck
-
-	etag := resp.Header.Get("ETag")
-	if etag == "" {
-		return "", errors.New("etag not found in response")
+		return nil, err
 	}
+	s.fetcher = fetcher
+	s.cacheFile = fetcher.CacheFile()
 
-	return etag, nil
+	return s, nil
 }
 
-func (s *SofaClient) saveEtag() error {
-	return os.WriteFile(s.etagFile, []byte(s.remoteEtag), 0644)
-}
-
-func (s *SofaClient) downloadFile(url, path string) error {
-	req, err := http.NewRequest("GET", url, http.NoBody)
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("User-Agent", s.userAgent)
-	req.Header.Set("Accept-Encoding", "gzip")
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close() // nolint: errcheck
-
-	file, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-
-	var reader io.ReadCloser
-	switch resp.Header.Get("Content-Encoding") {
-	case "gzip":
-		reader, err = gzip.NewReader(resp.Body)
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer func() {
-			if err := reader.Close(); err != nil {
-				log.Printf("close gzip reader: %v", err)
-			}
-		}()
-	default:
-		reader = resp.Body
-	}
-
-	defer file.Close() // nolint: errcheck
-	_, err = io.Copy(file, reader)
-	if err != nil {
-		return err
-	}
-
-	etag := resp.Header.Get("ETag")
-	if etag != "" {
-		s.remoteEtag = etag
-		err = s.saveEtag()
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+// fetch decodes the endpoint into v, downloading it only when the cached copy
+// is out of date.
+func (s *SofaClient) fetch(v interface{}) error {
+	return s.fetcher.Fetch(v)
 }
 
 func (s *SofaClient) downloadSofaJSON() (Root, error) {
-	valid, err := s.cacheValid()
-	if err != nil {
-		return Root{}, err
-	}
-
-	if valid {
-		return s.loadCachedData()
-	}
-
-	err = s.downloadData()
-	if err != nil {
+	var root Root
+	if err := s.fetch(&root); err != nil {
 		return Root{}, err
 	}
 
-	return s.loadCachedData()
+	return root, nil
 }
 
 func BuildUserAgent(version string) string {
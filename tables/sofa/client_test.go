@@ -5,13 +5,15 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	_ "embed"
 
-	"github.com/macadmins/osquery-extension/pkg/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 //go:embed test_data.json
@@ -20,15 +22,13 @@ var testData []byte
 //go:embed test_etag.txt
 var testEtag []byte
 
-const (
-	testCacheFile = "testCache.json"
-	testEtagFile  = "testetag.txt"
-)
-
 func setupTestServer() *httptest.Server {
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		etag := `W/"123456789"`
-		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == string(testEtag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", string(testEtag))
 		w.Write(testData) //nolint:errcheck
 	})
 
@@ -37,52 +37,57 @@ func setupTestServer() *httptest.Server {
 	return server
 }
 
-func setup() (server *httptest.Server) {
-	server = setupTestServer()
-	return server
-}
-
-func teardown(server *httptest.Server) {
-	server.Close()
-	removeTestFiles()
-}
-
-func removeTestFiles() {
-	_ = os.Remove(testCacheFile)
-	_ = os.Remove(testEtagFile)
-}
-
-func TestDownloadFile(t *testing.T) {
-	server := setup()
-	defer teardown(server)
-
-	cwd, err := os.Getwd()
-	assert.NoError(t, err)
+func TestDownloadSofaJSON(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
 
+	cacheDir := t.TempDir()
 	client, err := NewSofaClient(
 		WithURL(server.URL),
-		WithCacheDir(cwd),
+		WithCacheDir(cacheDir),
 		WithUserAgent("test"),
 	)
+	require.NoError(t, err)
 
+	var expectedRoot Root
+	require.NoError(t, json.Unmarshal(testData, &expectedRoot))
+
+	root, err := client.downloadSofaJSON()
 	assert.NoError(t, err)
+	assert.Equal(t, expectedRoot, root)
 
-	// Define a temporary file path for the test
-	tempFile := "temp.txt"
+	// Check that the feed and its etag were cached
+	assert.Equal(t, filepath.Join(cacheDir, "macos_data_feed", "data"), client.cacheFile)
+	assert.FileExists(t, client.cacheFile)
+	etag, err := os.ReadFile(filepath.Join(cacheDir, "macos_data_feed", "etag"))
+	assert.NoError(t, err)
+	assert.Equal(t, testEtag, etag)
+}
 
-	// Call the method under test
-	err = client.downloadFile(server.URL, tempFile)
+func TestDownloadSofaJSONFromCache(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 1 {
+			assert.Equal(t, string(testEtag), r.Header.Get("If-None-Match"))
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", string(testEtag))
+		w.Write(testData) //nolint:errcheck
+	}))
+	defer server.Close()
 
-	// Assert that no error occurred
-	assert.NoError(t, err)
+	cacheDir := t.TempDir()
+	client, err := NewSofaClient(WithURL(server.URL), WithCacheDir(cacheDir), WithUserAgent("test"))
+	require.NoError(t, err)
 
-	// Check that the file was created
-	_, err = os.Stat(tempFile)
-	assert.NoError(t, err)
+	first, err := client.downloadSofaJSON()
+	require.NoError(t, err)
 
-	// Clean up the temporary file
-	err = os.Remove(tempFile)
-	assert.NoError(t, err)
+	second, err := client.downloadSofaJSON()
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
 }
 
 func TestUnmarshalJSON(t *testing.T) {
@@ -121,263 +126,75 @@ func TestUnmarshalJSON(t *testing.T) {
 func TestWithUserAgent(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "Foo/2.0", r.Header.Get("User-Agent"))
+		w.Write([]byte(`{}`)) //nolint:errcheck
 	}))
-	path := t.TempDir()
-	client, err := NewSofaClient(WithUserAgent("Foo/2.0"), WithCacheDir(path))
-	assert.NoError(t, err)
-	assert.Equal(t, "Foo/2.0", client.userAgent)
-
-	err = client.downloadFile(server.URL, path+"/test.txt")
-	assert.NoError(t, err)
-}
-
-func TestLoacCachedEtag(t *testing.T) {
-	// Create a temporary file
-	tempEtagFile, err := os.CreateTemp("", "test")
-	assert.NoError(t, err)
-	defer func() {
-		assert.NoError(t, os.Remove(tempEtagFile.Name()))
-	}()
+	defer server.Close()
 
-	// Write the etag to the etag file
-	err = os.WriteFile(tempEtagFile.Name(), testEtag, 0644)
-	assert.NoError(t, err)
-
-	// Create a SofaClient
-	client := &SofaClient{
-		etagFile: tempEtagFile.Name(),
-	}
-
-	// Call the method under test
-	etag, err := client.loadCachedEtag()
-	assert.NoError(t, err)
-
-	// Assert that the etag was loaded correctly
-	assert.Equal(t, "W/\"123456789\"", etag)
-}
-
-func TestGetEtag(t *testing.T) {
-	server := setup()
-	defer teardown(server)
-
-	// Create a SofaClient
-	client := &SofaClient{
-		endpoint:   server.URL,
-		httpClient: http.DefaultClient,
-	}
-
-	// Call the method under test
-	etag, err := client.getEtag()
-	assert.NoError(t, err)
-
-	// Assert that the etag was returned correctly
-	assert.Equal(t, "W/\"123456789\"", etag)
-}
-
-func TestSaveEtag(t *testing.T) {
-	// Create a temporary file
-	tempEtagFile, err := os.CreateTemp("", "test")
-	assert.NoError(t, err)
-	defer func() {
-		assert.NoError(t, os.Remove(tempEtagFile.Name()))
-	}()
-
-	// Create a SofaClient
-	client := &SofaClient{
-		etagFile:   tempEtagFile.Name(),
-		remoteEtag: "W/\"123456789\"",
-		httpClient: http.DefaultClient,
-	}
-
-	// Call the method under test
-	err = client.saveEtag()
-	assert.NoError(t, err)
-
-	// Read the etag file
-	etagData, err := os.ReadFile(client.etagFile)
-	assert.NoError(t, err)
-
-	// Assert that the etag was saved correctly
-	assert.Equal(t, "W/\"123456789\"", string(etagData))
-}
-
-func TestLoadCachedData(t *testing.T) {
-	// Create a temporary file
-	tempCacheFile, err := os.CreateTemp("", "test")
-	assert.NoError(t, err)
-	defer func() {
-		assert.NoError(t, os.Remove(tempCacheFile.Name()))
-	}()
-
-	// Write the data to the cache file
-	err = os.WriteFile(tempCacheFile.Name(), testData, 0644)
-	assert.NoError(t, err)
-
-	// Create a SofaClient
-	client := &SofaClient{
-		cacheFile: tempCacheFile.Name(),
-	}
-
-	var expectedRoot Root
-	err = json.Unmarshal(testData, &expectedRoot)
-	assert.NoError(t, err)
-
-	// Call the method under test
-	data, err := client.loadCachedData()
-	assert.NoError(t, err)
-
-	// Assert that the data was loaded correctly
-	assert.Equal(t, expectedRoot, data)
-}
-
-func TestCacheValid(t *testing.T) {
-	server := setup()
-	defer teardown(server)
-	// Create a temporary file
-	tempCacheFile, err := os.CreateTemp("", "test")
-	assert.NoError(t, err)
-	defer func() {
-		assert.NoError(t, os.Remove(tempCacheFile.Name()))
-	}()
-
-	tempEtagFile, err := os.CreateTemp("", "test")
-	assert.NoError(t, err)
-	defer func() {
-		assert.NoError(t, os.Remove(tempEtagFile.Name()))
-	}()
-
-	// Create a SofaClient
-	client := &SofaClient{
-		endpoint:   server.URL,
-		cacheFile:  tempCacheFile.Name(),
-		etagFile:   tempEtagFile.Name(),
-		httpClient: http.DefaultClient,
-	}
-
-	// Download the data files
-	err = client.downloadData()
-	assert.NoError(t, err)
-
-	// Write the etag to the etag file
-	err = os.WriteFile(client.etagFile, testEtag, 0644)
-	assert.NoError(t, err)
-
-	// Call the method under test
-	valid, err := client.cacheValid()
-	// Assert that no error occurred
-	assert.NoError(t, err)
-
-	// Assert that the cache is valid
-	assert.True(t, valid)
-
-}
-
-func TestDownloadData(t *testing.T) {
-	server := setup()
-	defer teardown(server)
-
-	cwd, err := os.Getwd()
-	assert.NoError(t, err)
-
-	// Create a SofaClient
-	client := &SofaClient{
-		endpoint:   server.URL,
-		httpClient: http.DefaultClient,
-		cacheDir:   cwd,
-	}
-
-	err = client.createCacheDir()
-	assert.NoError(t, err)
-
-	client.setCachePaths()
-
-	// Call the method under test
-	err = client.downloadData()
-
-	// Assert that no error occurred
-	assert.NoError(t, err)
-	fs := utils.MockFileSystem{FileExists: true, Err: nil}
-	assert.True(t, utils.FileExists(fs, client.cacheFile))
-
-	// Check that the file was created
-	_, err = os.Stat(client.cacheFile)
+	client, err := NewSofaClient(WithUserAgent("Foo/2.0"), WithURL(server.URL), WithCacheDir(t.TempDir()))
 	assert.NoError(t, err)
+	assert.Equal(t, "Foo/2.0", client.userAgent)
 
-	// Clean up the temporary file
-	err = os.Remove(client.cacheFile)
+	_, err = client.downloadSofaJSON()
 	assert.NoError(t, err)
 }
 
 func TestNewSofaClient(t *testing.T) {
-	cwd, err := os.Getwd()
-	assert.NoError(t, err)
-	client, err := NewSofaClient(WithCacheDir(cwd), WithUserAgent("test"))
+	client, err := NewSofaClient(WithCacheDir(t.TempDir()), WithUserAgent("test"))
 	assert.NoError(t, err)
 	assert.Equal(t, SofaV1URL, client.endpoint)
 	assert.NotNil(t, client.httpClient)
+	assert.NotNil(t, client.fetcher)
+}
+
+func TestNewSofaClientRequiresUserAgent(t *testing.T) {
+	_, err := NewSofaClient(WithCacheDir(t.TempDir()))
+	assert.Error(t, err)
 }
 
 func TestWithHTTPClient(t *testing.T) {
-	cwd, err := os.Getwd()
-	assert.NoError(t, err)
-	client, err := NewSofaClient(WithHTTPClient(&http.Client{}), WithCacheDir(cwd), WithUserAgent("test"))
+	httpClient := &http.Client{}
+	client, err := NewSofaClient(WithHTTPClient(httpClient), WithCacheDir(t.TempDir()), WithUserAgent("test"))
 	assert.NoError(t, err)
-	assert.NotNil(t, client.httpClient)
+	assert.Equal(t, httpClient, client.httpClient)
 }
 
 func TestWithEndpoint(t *testing.T) {
-	cwd, err := os.Getwd()
-	assert.NoError(t, err)
-	client, err := NewSofaClient(WithURL("http://example.com"), WithCacheDir(cwd), WithUserAgent("test"))
+	client, err := NewSofaClient(WithURL("http://example.com"), WithCacheDir(t.TempDir()), WithUserAgent("test"))
 	assert.NoError(t, err)
 	assert.Equal(t, "http://example.com", client.endpoint)
 }
 
-func TestSetCachePaths(t *testing.T) {
-	cwd, err := os.Getwd()
-	assert.NoError(t, err)
-	client, err := NewSofaClient(WithCacheDir(cwd), WithUserAgent("test"))
-	assert.NoError(t, err)
-
-	client.setCachePaths()
-
-	assert.NotEmpty(t, client.cacheFile)
-	assert.NotEmpty(t, client.etagFile)
-}
-
 func TestWithCacheDir(t *testing.T) {
-	cwd, err := os.Getwd()
-	assert.NoError(t, err)
-	client, err := NewSofaClient(WithCacheDir(cwd), WithUserAgent("test"))
+	path := t.TempDir()
+	client, err := NewSofaClient(WithCacheDir(path), WithUserAgent("test"))
 	assert.NoError(t, err)
-	defer os.Remove(client.cacheDir) //nolint:errcheck
-
-	client.setCachePaths()
 
-	assert.NotEmpty(t, client.cachePath("test"))
+	assert.Equal(t, path, client.cacheDir)
+	assert.DirExists(t, filepath.Join(path, "macos_data_feed"))
 }
 
-func TestEtagPath(t *testing.T) {
-	cwd, err := os.Getwd()
-	assert.NoError(t, err)
-	client, err := NewSofaClient(WithCacheDir(cwd), WithUserAgent("test"))
+func TestWithCacheName(t *testing.T) {
+	path := t.TempDir()
+	client, err := NewSofaClient(WithCacheDir(path), WithUserAgent("test"), WithCacheName("ios_data_feed"))
 	assert.NoError(t, err)
 
-	client.setCachePaths()
-
-	assert.NotEmpty(t, client.etagPath("test"))
+	assert.Equal(t, filepath.Join(path, "ios_data_feed", "data"), client.cacheFile)
 }
 
-func TestCreateCacheDir(t *testing.T) {
-	cwd, err := os.Getwd()
-	assert.NoError(t, err)
-	client, err := NewSofaClient(WithCacheDir(cwd), WithUserAgent("test"))
-	assert.NoError(t, err)
+func TestWithLocalCache(t *testing.T) {
+	server := setupTestServer()
+	defer server.Close()
 
-	err = client.createCacheDir()
-	assert.NoError(t, err)
+	path := t.TempDir()
+	cacheFile := filepath.Join(path, "testCache.json")
+	etagFile := filepath.Join(path, "testetag.txt")
+	client, err := NewSofaClient(WithURL(server.URL), WithUserAgent("test"), WithLocalCache(cacheFile, etagFile))
+	require.NoError(t, err)
 
-	assert.DirExists(t, client.cacheDir)
+	_, err = client.downloadSofaJSON()
+	assert.NoError(t, err)
+	assert.FileExists(t, cacheFile)
+	assert.FileExists(t, etagFile)
 }
 
 func TestBuildUserAgent(t *testing.T) {
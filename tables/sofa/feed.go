@@ -0,0 +1,80 @@
+package sofa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	osquery "github.com/osquery/osquery-go"
+)
+
+const (
+	FeedMacOS = "macos"
+	FeedIOS   = "ios"
+)
+
+const SofaIOSV1URL = "https://sofafeed.macadmins.io/v1/ios_data_feed.json"
+
+// feedURLs maps each supported feed to its published Sofa data feed. Only
+// feeds with the OSVersions schema of the macOS feed can be listed here.
+var feedURLs = map[string]string{
+	FeedMacOS: SofaV1URL,
+	FeedIOS:   SofaIOSV1URL,
+}
+
+// feedURL returns the default data feed URL for the named feed.
+func feedURL(feed string) (string, error) {
+	url, ok := feedURLs[strings.ToLower(feed)]
+	if !ok {
+		feeds := make([]string, 0, len(feedURLs))
+		for name := range feedURLs {
+			feeds = append(feeds, name)
+		}
+		sort.Strings(feeds)
+		return "", fmt.Errorf("unknown sofa feed %q, expected one of %s", feed, strings.Join(feeds, ", "))
+	}
+	return url, nil
+}
+
+// feedCacheName returns the cache file base name for the named feed so each
+// feed is cached separately.
+func feedCacheName(feed string) string {
+	return strings.ToLower(feed) + "_data_feed"
+}
+
+// resolveOSVersion returns osVersion, or the running macOS version when none
+// was requested. Other feeds describe devices this extension cannot see, so
+// they require an explicit os_version.
+func resolveOSVersion(osVersion, feed, socketPath string) (string, error) {
+	if osVersion != "" {
+		return osVersion, nil
+	}
+
+	if feed != FeedMacOS {
+		return "", fmt.Errorf("os_version is required for the %s feed", feed)
+	}
+
+	// get the current device os version from osquery
+	osqueryClient, err := osquery.NewClient(socketPath, 10*time.Second)
+	if err != nil {
+		return "", err
+	}
+	defer osqueryClient.Close()
+
+	return getCurrentOSVersion(osqueryClient)
+}
+
+// loadFeed downloads (or loads from cache) the Sofa data feed at url, caching
+// it under a file name specific to feed.
+func loadFeed(url, feed string, opts ...Option) (Root, error) {
+	clientOpts := append([]Option{}, opts...)
+	clientOpts = append(clientOpts, WithURL(url), WithCacheName(feedCacheName(feed)))
+
+	client, err := NewSofaClient(clientOpts...)
+	if err != nil {
+		return Root{}, err
+	}
+
+	return client.downloadSofaJSON()
+}
@@ -0,0 +1,56 @@
+package sofa
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeedURL(t *testing.T) {
+	url, err := feedURL("macos")
+	assert.NoError(t, err)
+	assert.Equal(t, SofaV1URL, url)
+
+	url, err = feedURL("IOS")
+	assert.NoError(t, err)
+	assert.Equal(t, SofaIOSV1URL, url)
+
+	_, err = feedURL("safari")
+	assert.Error(t, err)
+
+	_, err = feedURL("watchos")
+	assert.Error(t, err)
+}
+
+func TestFeedCacheName(t *testing.T) {
+	assert.Equal(t, "macos_data_feed", feedCacheName(FeedMacOS))
+	assert.Equal(t, "ios_data_feed", feedCacheName("iOS"))
+}
+
+func TestResolveOSVersion(t *testing.T) {
+	osVersion, err := resolveOSVersion("17.4.1", FeedIOS, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "17.4.1", osVersion)
+
+	_, err = resolveOSVersion("", FeedIOS, "")
+	assert.Error(t, err)
+}
+
+func TestLoadFeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `W/"123456789"`)
+		w.Write(testData) //nolint:errcheck
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	root, err := loadFeed(server.URL, FeedIOS, WithUserAgent("test"), WithCacheDir(cacheDir))
+	require.NoError(t, err)
+	assert.NotEmpty(t, root.OSVersions)
+	assert.FileExists(t, filepath.Join(cacheDir, "ios_data_feed", "data"))
+	assert.NoDirExists(t, filepath.Join(cacheDir, "macos_data_feed"))
+}
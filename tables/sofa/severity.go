@@ -0,0 +1,72 @@
+package sofa
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+// CVESeverity is a single entry of an offline CVE severity dataset. The
+// dataset is a JSON object keyed by CVE identifier, for example:
+//
+//	{"CVE-2024-23225": {"cvss_score": 7.8, "cvss_severity": "HIGH", "epss_score": 0.0012, "known_exploited": true, "kev_date_added": "2024-03-06"}}
+type CVESeverity struct {
+	CVSSScore      float64 `json:"cvss_score"`
+	CVSSSeverity   string  `json:"cvss_severity"`
+	EPSSScore      float64 `json:"epss_score"`
+	KnownExploited bool    `json:"known_exploited"`
+	KEVDateAdded   string  `json:"kev_date_added"`
+}
+
+// SeverityData maps CVE identifiers to their severity information.
+type SeverityData map[string]CVESeverity
+
+func isRemoteSource(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// severityCacheName returns the cache directory name for a remote severity
+// dataset so datasets from different URLs are cached separately.
+func severityCacheName(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return "cve_severity_" + hex.EncodeToString(sum[:8])
+}
+
+// loadSeverityData reads the severity dataset from source. Local paths are read
+// directly, URLs are downloaded and cached alongside the Sofa feed.
+func loadSeverityData(source string, opts ...Option) (SeverityData, error) {
+	if !isRemoteSource(source) {
+		return loadSeverityFile(source)
+	}
+
+	clientOpts := append([]Option{}, opts...)
+	clientOpts = append(clientOpts, WithURL(source), WithCacheName(severityCacheName(source)))
+
+	client, err := NewSofaClient(clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var data SeverityData
+	if err := client.fetch(&data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func loadSeverityFile(path string) (SeverityData, error) {
+	jsonData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var data SeverityData
+	if err := json.Unmarshal(jsonData, &data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
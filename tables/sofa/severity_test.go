@@ -0,0 +1,91 @@
+package sofa
+
+import (
+	_ "embed"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed test_severity.json
+var testSeverity []byte
+
+func TestLoadSeverityFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "severity.json")
+	require.NoError(t, os.WriteFile(path, testSeverity, 0644))
+
+	data, err := loadSeverityData(path)
+	require.NoError(t, err)
+	assert.Len(t, data, 2)
+	assert.Equal(t, CVESeverity{
+		CVSSScore:      7.8,
+		CVSSSeverity:   "HIGH",
+		EPSSScore:      0.0012,
+		KnownExploited: true,
+		KEVDateAdded:   "2024-03-06",
+	}, data["CVE-2024-23225"])
+}
+
+func TestLoadSeverityFileMissing(t *testing.T) {
+	_, err := loadSeverityData(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestLoadSeverityDataFromURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `W/"severity"`)
+		w.Write(testSeverity) //nolint:errcheck
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	data, err := loadSeverityData(server.URL, WithUserAgent("test"), WithCacheDir(cacheDir))
+	require.NoError(t, err)
+	assert.True(t, data["CVE-2024-23225"].KnownExploited)
+	assert.FileExists(t, filepath.Join(cacheDir, severityCacheName(server.URL), "data"))
+	assert.FileExists(t, filepath.Join(cacheDir, severityCacheName(server.URL), "etag"))
+}
+
+func TestLoadSeverityDataSeparateCaches(t *testing.T) {
+	serve := func(body string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `W/"`+body+`"`)
+			w.Write([]byte(`{"` + body + `": {"cvss_severity": "HIGH"}}`)) //nolint:errcheck
+		}))
+	}
+	first := serve("CVE-2024-0001")
+	second := serve("CVE-2024-0002")
+
+	cacheDir := t.TempDir()
+	opts := []Option{WithUserAgent("test"), WithCacheDir(cacheDir)}
+	for _, server := range []*httptest.Server{first, second} {
+		_, err := loadSeverityData(server.URL, opts...)
+		require.NoError(t, err)
+	}
+	assert.NotEqual(t, severityCacheName(first.URL), severityCacheName(second.URL))
+
+	// Offline, each URL falls back to its own cached dataset.
+	first.Close()
+	second.Close()
+
+	data, err := loadSeverityData(first.URL, opts...)
+	require.NoError(t, err)
+	assert.Contains(t, data, "CVE-2024-0001")
+	assert.NotContains(t, data, "CVE-2024-0002")
+
+	data, err = loadSeverityData(second.URL, opts...)
+	require.NoError(t, err)
+	assert.Contains(t, data, "CVE-2024-0002")
+	assert.NotContains(t, data, "CVE-2024-0001")
+}
+
+func TestIsRemoteSource(t *testing.T) {
+	assert.True(t, isRemoteSource("https://example.com/severity.json"))
+	assert.True(t, isRemoteSource("http://example.com/severity.json"))
+	assert.False(t, isRemoteSource("/var/db/severity.json"))
+}
@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/hashicorp/go-version"
-	osquery "github.com/osquery/osquery-go"
 	"github.com/osquery/osquery-go/plugin/table"
 )
 
@@ -14,6 +13,9 @@ type UnpatchedCVE struct {
 	CVE               string
 	PatchedVersion    string
 	ActivelyExploited bool
+	ReleaseDate       string
+	UpdateName        string
+	SecurityInfo      string
 }
 
 func SofaUnpatchedCVEsColumns() []table.ColumnDefinition {
@@ -22,80 +24,109 @@ func SofaUnpatchedCVEsColumns() []table.ColumnDefinition {
 		table.TextColumn("cve"),
 		table.TextColumn("patched_version"),
 		table.TextColumn("actively_exploited"),
+		table.TextColumn("release_date"),
+		table.IntegerColumn("days_exposed"),
+		table.TextColumn("update_name"),
+		table.TextColumn("security_info"),
+		table.DoubleColumn("cvss_score"),
+		table.TextColumn("cvss_severity"),
+		table.DoubleColumn("epss_score"),
+		table.TextColumn("known_exploited"),
+		table.TextColumn("kev_date_added"),
+		table.TextColumn("severity_source"),
+		table.TextColumn("feed"),
 		table.TextColumn("url"),
 	}
 }
 
 func SofaUnpatchedCVEsGenerate(ctx context.Context, queryContext table.QueryContext, socketPath string, opts ...Option) ([]map[string]string, error) {
-	url := SofaV1URL
-	if constraintList, present := queryContext.Constraints["url"]; present {
-		// 'url' is in the where clause
-		for _, constraint := range constraintList.Constraints {
-			// =
-			if constraint.Operator == table.OperatorEquals {
-				url = constraint.Expression
-			}
-		}
+	url, osVersion, feed, err := processContextConstraints(queryContext)
+	if err != nil {
+		return nil, err
 	}
-	osVersion := ""
-	if constraintList, present := queryContext.Constraints["os_version"]; present {
-		// 'os_version' is in the where clause
+	severitySource := ""
+	if constraintList, present := queryContext.Constraints["severity_source"]; present {
+		// 'severity_source' is in the where clause
 		for _, constraint := range constraintList.Constraints {
 			// =
 			if constraint.Operator == table.OperatorEquals {
-				osVersion = constraint.Expression
+				severitySource = constraint.Expression
 			}
 		}
 	}
 
-	if osVersion == "" {
-		// get the current device os version from osquery
-		osqueryClient, err := osquery.NewClient(socketPath, 10*time.Second)
-		if err != nil {
-			return nil, err
-		}
-		defer osqueryClient.Close()
+	osVersion, err = resolveOSVersion(osVersion, feed, socketPath)
+	if err != nil {
+		return nil, err
+	}
 
-		osVersion, err = getCurrentOSVersion(osqueryClient)
+	var severity SeverityData
+	if severitySource != "" {
+		severity, err = loadSeverityData(severitySource, opts...)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	defaultOpts := []Option{
-		WithURL(url),
-	}
-	opts = append(opts, defaultOpts...)
-
-	client, err := NewSofaClient(opts...)
+	root, err := loadFeed(url, feed, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	root, err := client.downloadSofaJSON()
-	if err != nil {
-		return nil, err
-	}
-
-	var results []map[string]string
-
 	// get all unpatched cves (for any os version that is higher than the current os version)
 	unpatchedCVEs, err := getUnpatchedCVEs(root, osVersion)
 	if err != nil {
 		return nil, err
 	}
 
+	return buildUnpatchedCVEsOutput(unpatchedCVEs, severity, osVersion, feed, url, severitySource, time.Now()), nil
+}
+
+func buildUnpatchedCVEsOutput(unpatchedCVEs []UnpatchedCVE, severity SeverityData, osVersion, feed, url, severitySource string, now time.Time) []map[string]string {
+	var results []map[string]string
 	for _, unpatchedCVE := range unpatchedCVEs {
-		results = append(results, map[string]string{
+		row := map[string]string{
 			"os_version":         osVersion,
 			"cve":                unpatchedCVE.CVE,
 			"patched_version":    unpatchedCVE.PatchedVersion,
 			"actively_exploited": strconv.FormatBool(unpatchedCVE.ActivelyExploited),
+			"release_date":       unpatchedCVE.ReleaseDate,
+			"days_exposed":       daysSince(unpatchedCVE.ReleaseDate, now),
+			"update_name":        unpatchedCVE.UpdateName,
+			"security_info":      unpatchedCVE.SecurityInfo,
+			"cvss_score":         "",
+			"cvss_severity":      "",
+			"epss_score":         "",
+			"known_exploited":    "",
+			"kev_date_added":     "",
+			"severity_source":    severitySource,
+			"feed":               feed,
 			"url":                url,
-		})
+		}
+		if info, ok := severity[unpatchedCVE.CVE]; ok {
+			row["cvss_score"] = strconv.FormatFloat(info.CVSSScore, 'f', -1, 64)
+			row["cvss_severity"] = info.CVSSSeverity
+			row["epss_score"] = strconv.FormatFloat(info.EPSSScore, 'f', -1, 64)
+			row["known_exploited"] = strconv.FormatBool(info.KnownExploited)
+			row["kev_date_added"] = info.KEVDateAdded
+		}
+		results = append(results, row)
 	}
+	return results
+}
 
-	return results, nil
+// daysSince returns the number of whole days between the Sofa release date and
+// now, or an empty string when the release date cannot be parsed.
+func daysSince(releaseDate string, now time.Time) string {
+	released, err := time.Parse(time.RFC3339, releaseDate)
+	if err != nil {
+		return ""
+	}
+	days := int(now.Sub(released).Hours() / 24)
+	if days < 0 {
+		days = 0
+	}
+	return strconv.Itoa(days)
 }
 
 func getUnpatchedCVEs(root Root, osVersion string) ([]UnpatchedCVE, error) {
@@ -124,6 +155,9 @@ func getUnpatchedCVEs(root Root, osVersion string) ([]UnpatchedCVE, error) {
 					CVE:               name,
 					PatchedVersion:    securityRelease.ProductVersion,
 					ActivelyExploited: activelyExploited,
+					ReleaseDate:       securityRelease.ReleaseDate,
+					UpdateName:        securityRelease.UpdateName,
+					SecurityInfo:      securityRelease.SecurityInfo,
 				})
 			}
 
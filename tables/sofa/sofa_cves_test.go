@@ -2,6 +2,7 @@ package sofa
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -22,6 +23,9 @@ func TestGetUnpatchedCVEs(t *testing.T) {
 						SecurityReleases: []SecurityRelease{
 							{
 								ProductVersion: "10.1",
+								ReleaseDate:    "2024-03-07T00:00:00Z",
+								UpdateName:     "Test OS 10.1",
+								SecurityInfo:   "https://support.apple.com/kb/HT000001",
 								CVEs: map[string]bool{
 									"CVE-1234": true,
 								},
@@ -36,6 +40,9 @@ func TestGetUnpatchedCVEs(t *testing.T) {
 					CVE:               "CVE-1234",
 					PatchedVersion:    "10.1",
 					ActivelyExploited: true,
+					ReleaseDate:       "2024-03-07T00:00:00Z",
+					UpdateName:        "Test OS 10.1",
+					SecurityInfo:      "https://support.apple.com/kb/HT000001",
 				},
 			},
 			wantErr: false,
@@ -74,3 +81,63 @@ func TestGetUnpatchedCVEs(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildUnpatchedCVEsOutput(t *testing.T) {
+	unpatchedCVEs := []UnpatchedCVE{
+		{
+			CVE:               "CVE-2024-23225",
+			PatchedVersion:    "14.4",
+			ActivelyExploited: true,
+			ReleaseDate:       "2024-03-07T00:00:00Z",
+			UpdateName:        "macOS Sonoma 14.4",
+			SecurityInfo:      "https://support.apple.com/kb/HT214084",
+		},
+		{
+			CVE:            "CVE-2024-0001",
+			PatchedVersion: "14.4",
+			ReleaseDate:    "2024-03-07T00:00:00Z",
+		},
+	}
+	severity := SeverityData{
+		"CVE-2024-23225": {
+			CVSSScore:      7.8,
+			CVSSSeverity:   "HIGH",
+			EPSSScore:      0.0012,
+			KnownExploited: true,
+			KEVDateAdded:   "2024-03-06",
+		},
+	}
+	now := time.Date(2024, 3, 17, 12, 0, 0, 0, time.UTC)
+
+	output := buildUnpatchedCVEsOutput(unpatchedCVEs, severity, "14.3", FeedMacOS, SofaV1URL, "/tmp/severity.json", now)
+
+	assert.Len(t, output, 2)
+	assert.Equal(t, map[string]string{
+		"os_version":         "14.3",
+		"cve":                "CVE-2024-23225",
+		"patched_version":    "14.4",
+		"actively_exploited": "true",
+		"release_date":       "2024-03-07T00:00:00Z",
+		"days_exposed":       "10",
+		"update_name":        "macOS Sonoma 14.4",
+		"security_info":      "https://support.apple.com/kb/HT214084",
+		"cvss_score":         "7.8",
+		"cvss_severity":      "HIGH",
+		"epss_score":         "0.0012",
+		"known_exploited":    "true",
+		"kev_date_added":     "2024-03-06",
+		"severity_source":    "/tmp/severity.json",
+		"feed":               FeedMacOS,
+		"url":                SofaV1URL,
+	}, output[0])
+	assert.Equal(t, "", output[1]["cvss_score"])
+	assert.Equal(t, "", output[1]["known_exploited"])
+}
+
+func TestDaysSince(t *testing.T) {
+	now := time.Date(2024, 3, 17, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, "10", daysSince("2024-03-07T00:00:00Z", now))
+	assert.Equal(t, "0", daysSince("2024-03-20T00:00:00Z", now))
+	assert.Equal(t, "", daysSince("", now))
+	assert.Equal(t, "", daysSince("not a date", now))
+}
@@ -4,11 +4,10 @@ import (
 	"context"
 	"errors"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/hashicorp/go-version"
 	"github.com/macadmins/osquery-extension/pkg/utils"
-	osquery "github.com/osquery/osquery-go"
 	"github.com/osquery/osquery-go/plugin/table"
 )
 
@@ -123,38 +122,23 @@ func SofaSecurityReleaseInfoColumns() []table.ColumnDefinition {
 		table.IntegerColumn("unique_cves_count"),
 		table.IntegerColumn("days_since_previous_release"),
 		table.TextColumn("os_version"),
+		table.TextColumn("feed"),
 		table.TextColumn("url"),
 	}
 }
 
 func SofaSecurityReleaseInfoGenerate(ctx context.Context, queryContext table.QueryContext, socketPath string, clientOpts ...Option) ([]map[string]string, error) {
-	url, osVersion := processContextConstraints(queryContext)
-
-	if osVersion == "" {
-		// get the current device os version from osquery
-		osqueryClient, err := osquery.NewClient(socketPath, 10*time.Second)
-		if err != nil {
-			return nil, err
-		}
-		defer osqueryClient.Close()
-
-		osVersion, err = getCurrentOSVersion(osqueryClient)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	defaultOpts := []Option{
-		WithURL(url),
+	url, osVersion, feed, err := processContextConstraints(queryContext)
+	if err != nil {
+		return nil, err
 	}
-	clientOpts = append(clientOpts, defaultOpts...)
 
-	client, err := NewSofaClient(clientOpts...)
+	osVersion, err = resolveOSVersion(osVersion, feed, socketPath)
 	if err != nil {
 		return nil, err
 	}
 
-	root, err := client.downloadSofaJSON()
+	root, err := loadFeed(url, feed, clientOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -165,10 +149,10 @@ func SofaSecurityReleaseInfoGenerate(ctx context.Context, queryContext table.Que
 		return nil, err
 	}
 
-	return buildSecurityReleaseInfoOutput(securityReleases, osVersion, url), nil
+	return buildSecurityReleaseInfoOutput(securityReleases, osVersion, feed, url), nil
 }
 
-func buildSecurityReleaseInfoOutput(securityReleases []SecurityRelease, osVersion, url string) []map[string]string {
+func buildSecurityReleaseInfoOutput(securityReleases []SecurityRelease, osVersion, feed, url string) []map[string]string {
 	var results []map[string]string
 	for _, securityRelease := range securityReleases {
 		results = append(results, map[string]string{
@@ -179,14 +163,17 @@ func buildSecurityReleaseInfoOutput(securityReleases []SecurityRelease, osVersio
 			"unique_cves_count":           strconv.Itoa(securityRelease.UniqueCVEsCount),
 			"days_since_previous_release": strconv.Itoa(securityRelease.DaysSincePreviousRelease),
 			"os_version":                  osVersion,
+			"feed":                        feed,
 			"url":                         url,
 		})
 	}
 	return results
 }
 
-func processContextConstraints(queryContext table.QueryContext) (string, string) {
-	url := SofaV1URL
+// processContextConstraints returns the url, os_version and feed constraints.
+// When no url is given, the published data feed for the requested feed is used.
+func processContextConstraints(queryContext table.QueryContext) (string, string, string, error) {
+	url := ""
 	if constraintList, present := queryContext.Constraints["url"]; present {
 		// 'url' is in the where clause
 		for _, constraint := range constraintList.Constraints {
@@ -206,8 +193,26 @@ func processContextConstraints(queryContext table.QueryContext) (string, string)
 			}
 		}
 	}
+	feed := FeedMacOS
+	if constraintList, present := queryContext.Constraints["feed"]; present {
+		// 'feed' is in the where clause
+		for _, constraint := range constraintList.Constraints {
+			// =
+			if constraint.Operator == table.OperatorEquals {
+				feed = strings.ToLower(constraint.Expression)
+			}
+		}
+	}
+
+	defaultURL, err := feedURL(feed)
+	if err != nil {
+		return "", "", "", err
+	}
+	if url == "" {
+		url = defaultURL
+	}
 
-	return url, osVersion
+	return url, osVersion, feed, nil
 }
 
 func getSecurityReleaseInfoForOSVersion(root Root, osVersion string) ([]SecurityRelease, error) {
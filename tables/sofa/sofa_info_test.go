@@ -126,10 +126,38 @@ func TestProcessContextConstraints(t *testing.T) {
 		},
 	}
 
-	url, osVersion := processContextConstraints(queryContext)
+	url, osVersion, feed, err := processContextConstraints(queryContext)
 
+	assert.NoError(t, err)
 	assert.Equal(t, "http://testurl.com", url)
 	assert.Equal(t, "14.5.1", osVersion)
+	assert.Equal(t, FeedMacOS, feed)
+}
+
+func TestProcessContextConstraintsFeed(t *testing.T) {
+	queryContext := table.QueryContext{
+		Constraints: map[string]table.ConstraintList{
+			"feed": {
+				Constraints: []table.Constraint{
+					{
+						Operator:   table.OperatorEquals,
+						Expression: "iOS",
+					},
+				},
+			},
+		},
+	}
+
+	url, osVersion, feed, err := processContextConstraints(queryContext)
+
+	assert.NoError(t, err)
+	assert.Equal(t, SofaIOSV1URL, url)
+	assert.Equal(t, "", osVersion)
+	assert.Equal(t, FeedIOS, feed)
+
+	queryContext.Constraints["feed"].Constraints[0].Expression = "tvos"
+	_, _, _, err = processContextConstraints(queryContext)
+	assert.Error(t, err)
 }
 
 func TestBuildSecurityReleaseInfoOutput(t *testing.T) {
@@ -155,11 +183,12 @@ func TestBuildSecurityReleaseInfoOutput(t *testing.T) {
 			"unique_cves_count":           "3",
 			"days_since_previous_release": "30",
 			"os_version":                  "14.5.1",
+			"feed":                        FeedMacOS,
 			"url":                         SofaV1URL,
 		},
 	}
 
-	output := buildSecurityReleaseInfoOutput(securityReleases, osVersion, SofaV1URL)
+	output := buildSecurityReleaseInfoOutput(securityReleases, osVersion, FeedMacOS, SofaV1URL)
 
 	assert.Equal(t, expectedOutput, output)
 }
@@ -0,0 +1,136 @@
+package sofa
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/hashicorp/go-version"
+	"github.com/osquery/osquery-go/plugin/table"
+)
+
+// VersionStatus describes how an OS version compares to the latest release of
+// its major version in a Sofa feed.
+type VersionStatus struct {
+	OSVersion                  string
+	OSLine                     string
+	Supported                  bool
+	LatestVersion              string
+	LatestBuild                string
+	LatestReleaseDate          string
+	IsLatest                   bool
+	SecurityReleasesBehind     int
+	UnpatchedCVEsCount         int
+	ActivelyExploitedCVEsCount int
+}
+
+func SofaVersionStatusColumns() []table.ColumnDefinition {
+	return []table.ColumnDefinition{
+		table.TextColumn("os_version"),
+		table.TextColumn("os_line"),
+		table.TextColumn("supported"),
+		table.TextColumn("latest_version"),
+		table.TextColumn("latest_build"),
+		table.TextColumn("latest_release_date"),
+		table.TextColumn("is_latest"),
+		table.IntegerColumn("security_releases_behind"),
+		table.IntegerColumn("unpatched_cves_count"),
+		table.IntegerColumn("actively_exploited_cves_count"),
+		table.TextColumn("feed"),
+		table.TextColumn("url"),
+	}
+}
+
+func SofaVersionStatusGenerate(ctx context.Context, queryContext table.QueryContext, socketPath string, clientOpts ...Option) ([]map[string]string, error) {
+	url, osVersion, feed, err := processContextConstraints(queryContext)
+	if err != nil {
+		return nil, err
+	}
+
+	osVersion, err = resolveOSVersion(osVersion, feed, socketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := loadFeed(url, feed, clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := evaluateOSVersion(root, osVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return []map[string]string{
+		{
+			"os_version":                    status.OSVersion,
+			"os_line":                       status.OSLine,
+			"supported":                     strconv.FormatBool(status.Supported),
+			"latest_version":                status.LatestVersion,
+			"latest_build":                  status.LatestBuild,
+			"latest_release_date":           status.LatestReleaseDate,
+			"is_latest":                     strconv.FormatBool(status.IsLatest),
+			"security_releases_behind":      strconv.Itoa(status.SecurityReleasesBehind),
+			"unpatched_cves_count":          strconv.Itoa(status.UnpatchedCVEsCount),
+			"actively_exploited_cves_count": strconv.Itoa(status.ActivelyExploitedCVEsCount),
+			"feed":                          feed,
+			"url":                           url,
+		},
+	}, nil
+}
+
+// evaluateOSVersion compares osVersion against the OS line in root sharing its
+// major version. A version whose major line is not in the feed is reported as
+// unsupported.
+func evaluateOSVersion(root Root, osVersion string) (VersionStatus, error) {
+	status := VersionStatus{OSVersion: osVersion}
+	parsedOSVersion, err := version.NewVersion(osVersion)
+	if err != nil {
+		return status, err
+	}
+
+	for _, os := range root.OSVersions {
+		parsedLatest, err := version.NewVersion(os.Latest.ProductVersion)
+		if err != nil {
+			continue
+		}
+		if parsedLatest.Segments()[0] != parsedOSVersion.Segments()[0] {
+			continue
+		}
+
+		status.OSLine = os.OSVersion
+		status.Supported = true
+		status.LatestVersion = os.Latest.ProductVersion
+		status.LatestBuild = os.Latest.Build
+		status.LatestReleaseDate = os.Latest.ReleaseDate
+		status.IsLatest = parsedOSVersion.GreaterThanOrEqual(parsedLatest)
+
+		for _, securityRelease := range os.SecurityReleases {
+			parsedProductVersion, err := version.NewVersion(securityRelease.ProductVersion)
+			if err != nil {
+				return status, err
+			}
+			if parsedProductVersion.GreaterThan(parsedOSVersion) {
+				status.SecurityReleasesBehind++
+			}
+		}
+		break
+	}
+
+	unpatchedCVEs, err := getUnpatchedCVEs(root, osVersion)
+	if err != nil {
+		return status, err
+	}
+	exploited := map[string]bool{}
+	for _, unpatchedCVE := range unpatchedCVEs {
+		exploited[unpatchedCVE.CVE] = exploited[unpatchedCVE.CVE] || unpatchedCVE.ActivelyExploited
+	}
+	status.UnpatchedCVEsCount = len(exploited)
+	for _, activelyExploited := range exploited {
+		if activelyExploited {
+			status.ActivelyExploitedCVEsCount++
+		}
+	}
+
+	return status, nil
+}
@@ -0,0 +1,70 @@
+package sofa
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateOSVersion(t *testing.T) {
+	var root Root
+	require.NoError(t, json.Unmarshal(testData, &root))
+
+	tests := []struct {
+		name      string
+		osVersion string
+		want      VersionStatus
+	}{
+		{
+			name:      "behind latest",
+			osVersion: "14.3.1",
+			want: VersionStatus{
+				OSVersion:                  "14.3.1",
+				OSLine:                     "Sonoma 14",
+				Supported:                  true,
+				LatestVersion:              "14.4.1",
+				LatestBuild:                "23E224",
+				LatestReleaseDate:          "2024-03-25T00:00:00Z",
+				IsLatest:                   false,
+				SecurityReleasesBehind:     2,
+				UnpatchedCVEsCount:         68,
+				ActivelyExploitedCVEsCount: 2,
+			},
+		},
+		{
+			name:      "latest",
+			osVersion: "14.4.1",
+			want: VersionStatus{
+				OSVersion:         "14.4.1",
+				OSLine:            "Sonoma 14",
+				Supported:         true,
+				LatestVersion:     "14.4.1",
+				LatestBuild:       "23E224",
+				LatestReleaseDate: "2024-03-25T00:00:00Z",
+				IsLatest:          true,
+			},
+		},
+		{
+			name:      "unsupported major version",
+			osVersion: "11.7.10",
+			want: VersionStatus{
+				OSVersion: "11.7.10",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := evaluateOSVersion(root, tt.osVersion)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEvaluateOSVersionInvalid(t *testing.T) {
+	_, err := evaluateOSVersion(Root{}, "not a version")
+	assert.Error(t, err)
+}